@@ -1,12 +1,14 @@
 package analyze
 
 import (
+	"context"
 	"fmt"
-	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/wbreza/azd-template-analysis/project"
@@ -29,38 +31,82 @@ func NewSegment() *Segment {
 	}
 }
 
+type AnalysisStatus string
+
+const (
+	AnalysisSucceeded AnalysisStatus = "analyzed"
+	AnalysisFailed    AnalysisStatus = "failed"
+	// AnalysisNotSynced is the status of templates that failed to sync and were not analyzed.
+	AnalysisNotSynced AnalysisStatus = "notSynced"
+)
+
 type TemplateWithResults struct {
 	Template *templates.Template `json:"template"`
+	Commit   string              `json:"commit,omitempty"`
+	Status   AnalysisStatus      `json:"status"`
 	Analysis *Segment            `json:"analysis"`
 }
 
 type AnalysisContext struct {
 	WorkingDirectory string
+	// Analyzers run for every template in order, all analyzers of the default registry when empty.
+	Analyzers []Analyzer
+	// Rules are the heuristic rules evaluated for every template, the default rules when nil.
+	Rules *RuleSet
+}
+
+// TemplatePath returns the directory of the template clone within the working directory.
+func (analysisCtx AnalysisContext) TemplatePath(template *templates.Template) (string, error) {
+	return templates.Dir(analysisCtx.WorkingDirectory, template.Source)
+}
+
+var defaultRules = DefaultRules()
+
+func (analysisCtx AnalysisContext) analyzers() ([]Analyzer, error) {
+	if len(analysisCtx.Analyzers) == 0 {
+		return DefaultRegistry.Resolve(nil, nil)
+	}
+
+	return analysisCtx.Analyzers, nil
 }
 
-type analysisFunc func(ctx AnalysisContext, template *templates.Template, analysis *Segment) error
+func (analysisCtx AnalysisContext) rules() *RuleSet {
+	if analysisCtx.Rules == nil {
+		return defaultRules
+	}
 
-var heuristicMap = map[string]regexp.Regexp{
-	"usesAzCli":      *regexp.MustCompile(`az\s`),
-	"usesAzCliLogin": *regexp.MustCompile(`az\slogin`),
-	"usesAzd":        *regexp.MustCompile(`azd\s`),
+	return analysisCtx.Rules
 }
 
-func AnalyzeTemplate(ctx AnalysisContext, template *templates.Template) (*Segment, error) {
+// AnalyzeTemplate runs the analyzers of the analysis context for the template.
+// Analysis stops and returns an error when the context is cancelled or times out.
+func AnalyzeTemplate(ctx context.Context, analysisCtx AnalysisContext, template *templates.Template) (*Segment, error) {
+	return analyzeTemplateContext(ctx, NewTemplateContext(analysisCtx, template))
+}
+
+// analyzeTemplateContext runs the analyzers with the template context shared between them.
+func analyzeTemplateContext(ctx context.Context, templateCtx *TemplateContext) (*Segment, error) {
 	root := NewSegment()
 
-	analysisFuncs := []analysisFunc{
-		analyzeHooks,
-		analyzeProject,
-		analyzeTemplate,
+	analyzers, err := templateCtx.analyzers()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, analyzeFunc := range analysisFuncs {
-		if err := analyzeFunc(ctx, template, root); err != nil {
+	for _, analyzer := range analyzers {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("analysis stopped: %w", err)
+		}
+
+		if err := analyzer.Analyze(ctx, templateCtx, root); err != nil {
 			root.Errors = append(root.Errors, err.Error())
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("analysis stopped: %w", err)
+	}
+
 	return root, nil
 }
 
@@ -109,8 +155,9 @@ func GetInsight[T comparable](analysis *Segment, key string) ([]T, bool) {
 		}
 	}
 
-	for _, segment := range analysis.Segments {
-		childResults, has := GetInsight[T](segment, key)
+	// Child segments are visited in key order so the first result is stable across runs
+	for _, segmentKey := range slices.Sorted(maps.Keys(analysis.Segments)) {
+		childResults, has := GetInsight[T](analysis.Segments[segmentKey], key)
 		if has {
 			results = append(results, childResults...)
 		}
@@ -119,7 +166,8 @@ func GetInsight[T comparable](analysis *Segment, key string) ([]T, bool) {
 	return results, len(results) > 0
 }
 
-func analyzeTemplate(ctx AnalysisContext, template *templates.Template, analysis *Segment) error {
+func analyzeTemplate(ctx context.Context, templateCtx *TemplateContext, analysis *Segment) error {
+	template := templateCtx.Template
 	templateSegment := NewSegment()
 	analysis.Segments["template"] = templateSegment
 
@@ -127,33 +175,40 @@ func analyzeTemplate(ctx AnalysisContext, template *templates.Template, analysis
 	templateSegment.Insights["isCommunity"] = NewInsight(BoolInsight, slices.Contains(template.Tags, "community"))
 	templateSegment.Insights["isMsft"] = NewInsight(BoolInsight, slices.Contains(template.Tags, "msft"))
 
-	templatePath := filepath.Join(ctx.WorkingDirectory, filepath.Base(template.Source))
-	azdProject, err := project.Load(templatePath)
-	templateSegment.Insights["hasAzureYaml"] = NewInsight(BoolInsight, azdProject != nil && err == nil)
+	if _, err := templateCtx.Path(); err != nil {
+		return err
+	}
 
-	analyzeFileSystem(ctx, template, templateSegment)
+	azdProject, err := templateCtx.Project()
+	templateSegment.Insights["hasAzureYaml"] = NewInsight(BoolInsight, azdProject != nil && err == nil)
 
-	return nil
+	return analyzeFileSystem(ctx, templateCtx, templateSegment)
 }
 
-func analyzeFileSystem(ctx AnalysisContext, template *templates.Template, root *Segment) error {
-	templatePath := filepath.Join(ctx.WorkingDirectory, filepath.Base(template.Source))
-	infraPath := filepath.Join(templatePath, "infra")
+func analyzeFileSystem(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		return err
+	}
+
+	files, err := templateCtx.Files(ctx)
+	if err != nil {
+		return err
+	}
 
 	root.Insights["hasInfra"] = NewInsight(BoolInsight, hasDir(templatePath, "infra"))
 	root.Insights["hasGithub"] = NewInsight(BoolInsight, hasDir(templatePath, ".github"))
 	root.Insights["hasAzdo"] = NewInsight(BoolInsight, hasDir(templatePath, ".azdo"))
 	root.Insights["hasDevcontainer"] = NewInsight(BoolInsight, hasDir(templatePath, ".devcontainer"))
 
-	root.Insights["infraBicep"] = NewInsight(BoolInsight, hasFilePattern(infraPath, "*.bicep"))
-	root.Insights["infraTerraform"] = NewInsight(BoolInsight, hasFilePattern(infraPath, "*.tf"))
+	root.Insights["infraBicep"] = NewInsight(BoolInsight, hasFilePattern(files, "infra", "*.bicep"))
+	root.Insights["infraTerraform"] = NewInsight(BoolInsight, hasFilePattern(files, "infra", "*.tf"))
 
 	return nil
 }
 
-func analyzeProject(ctx AnalysisContext, template *templates.Template, root *Segment) error {
-	templatePath := filepath.Join(ctx.WorkingDirectory, filepath.Base(template.Source))
-	azdProject, err := project.Load(templatePath)
+func analyzeProject(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	azdProject, err := templateCtx.Project()
 	if err != nil {
 		return err
 	}
@@ -168,36 +223,69 @@ func analyzeProject(ctx AnalysisContext, template *templates.Template, root *Seg
 	projectSegment.Insights["hasHooks"] = NewInsight(BoolInsight, HasInsightValue(root, "hasProjectHooks", true) || HasInsightValue(root, "hasServiceHooks", true))
 	projectSegment.Insights["hasWorkflows"] = NewInsight(BoolInsight, len(azdProject.Workflows) > 0)
 	projectSegment.Insights["hasMetadata"] = NewInsight(BoolInsight, azdProject.Metadata != nil)
-	projectSegment.Insights["hasServices"] = NewInsight(BoolInsight, len(azdProject.Services) > 0)
 
-	projectSegment.Insights["serviceCount"] = NewInsight(NumberInsight, len(azdProject.Services))
+	// Services of .NET Aspire app hosts are inferred from the app host program
+	deployedProject := *azdProject
+	deployedProject.Services = analyzeAspire(ctx, templateCtx, azdProject, projectSegment)
+
+	projectSegment.Insights["hasServices"] = NewInsight(BoolInsight, len(deployedProject.Services) > 0)
+
+	// Non-standard azure.yaml placement
+	projectSegment.Data["projectFile"] = azdProject.File
+	projectSegment.Data["projectFiles"] = azdProject.Files
+	projectSegment.Insights["usesAzureYml"] = NewInsight(BoolInsight, strings.HasSuffix(azdProject.File, ".yml"))
+	projectSegment.Insights["isNestedProject"] = NewInsight(BoolInsight, azdProject.IsNested())
+	projectSegment.Insights["hasMultipleProjects"] = NewInsight(BoolInsight, len(azdProject.Files) > 1)
+	projectSegment.Insights["projectFileCount"] = NewInsight(NumberInsight, len(azdProject.Files))
+
+	projectSegment.Insights["hasInfraConfig"] = NewInsight(BoolInsight, azdProject.Infra != nil)
+	projectSegment.Insights["hasPipelineConfig"] = NewInsight(BoolInsight, azdProject.Pipeline != nil)
+	projectSegment.Insights["hasRemoteState"] = NewInsight(BoolInsight, azdProject.State != nil && azdProject.State.Remote != nil)
+	projectSegment.Insights["hasRequiredVersions"] = NewInsight(BoolInsight, azdProject.RequiredVersions != nil)
+	projectSegment.Insights["hasPlatform"] = NewInsight(BoolInsight, azdProject.Platform != nil)
+	projectSegment.Insights["hasResources"] = NewInsight(BoolInsight, len(azdProject.Resources) > 0)
+	projectSegment.Insights["infraTerraformProvider"] = NewInsight(BoolInsight, azdProject.Infra != nil && azdProject.Infra.Provider == "terraform")
+
+	projectSegment.Insights["serviceCount"] = NewInsight(NumberInsight, len(deployedProject.Services))
+	projectSegment.Insights["resourceCount"] = NewInsight(NumberInsight, len(azdProject.Resources))
+
+	usesDocker, usesK8s, usesServiceEnv := false, false, false
+	for _, service := range azdProject.Services {
+		usesDocker = usesDocker || service.Docker != nil
+		usesK8s = usesK8s || service.K8s != nil
+		usesServiceEnv = usesServiceEnv || len(service.Env) > 0
+	}
+
+	violations := azdProject.ValidateSchema()
+	root.Segments["schema"] = newSchemaSegment(violations)
+
+	projectSegment.Insights["isSchemaValid"] = NewInsight(BoolInsight, len(violations) == 0)
+	projectSegment.Insights["schemaViolationCount"] = NewInsight(NumberInsight, len(violations))
+
+	projectSegment.Insights["usesDockerConfig"] = NewInsight(BoolInsight, usesDocker)
+	projectSegment.Insights["usesK8sConfig"] = NewInsight(BoolInsight, usesK8s)
+	projectSegment.Insights["usesServiceEnv"] = NewInsight(BoolInsight, usesServiceEnv)
 
 	hostTypes := []string{"appservice", "containerapp", "function", "springapp", "aks", "staticwebapp", "ai.endpoint"}
 	for _, hostType := range hostTypes {
-		projectSegment.Insights[fmt.Sprintf("host-%s", hostType)] = NewInsight(BoolInsight, hasHostType(*azdProject, hostType))
+		projectSegment.Insights[fmt.Sprintf("host-%s", hostType)] = NewInsight(BoolInsight, hasHostType(deployedProject, hostType))
 	}
 
-	languages := map[string][]string{
-		"dotnet":     {"csharp", "dotnet", "fsharp"},
-		"java":       {"java"},
-		"javascript": {"javascript", "node", "ts"},
-		"python":     {"python", "py"},
-	}
-	for key, languageSet := range languages {
-		projectSegment.Insights[fmt.Sprintf("lang-%s", key)] = NewInsight(BoolInsight, hasLanguage(*azdProject, languageSet))
+	for _, family := range []string{"dotnet", "java", "javascript", "python"} {
+		projectSegment.Insights[fmt.Sprintf("lang-%s", family)] = NewInsight(BoolInsight, hasLanguage(deployedProject, family))
 	}
 
 	return nil
 }
 
-func analyzeHooks(ctx AnalysisContext, template *templates.Template, root *Segment) error {
-	templatePath := filepath.Join(ctx.WorkingDirectory, filepath.Base(template.Source))
-	azdProject, err := project.Load(templatePath)
+func analyzeHooks(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	azdProject, err := templateCtx.Project()
 	if err != nil {
 		return err
 	}
 
 	hooksRootSegment := NewSegment()
+	hookRules := templateCtx.rules().HookRules()
 	hasProjectHooks := len(azdProject.Hooks) > 0
 
 	if hasProjectHooks {
@@ -205,7 +293,7 @@ func analyzeHooks(ctx AnalysisContext, template *templates.Template, root *Segme
 		hooksRootSegment.Segments["project"] = projectHooks
 
 		// Project Hooks
-		analyzeHooksMap(azdProject.Hooks, projectHooks, templatePath)
+		analyzeHooksMap(templateCtx, azdProject.Hooks, projectHooks, azdProject.Root, hookRules)
 	}
 
 	hasServiceHooks := false
@@ -221,8 +309,8 @@ func analyzeHooks(ctx AnalysisContext, template *templates.Template, root *Segme
 		serviceHooks.Segments[serviceName] = serviceSegment
 		hasServiceHooks = true
 
-		servicePath := filepath.Join(templatePath, service.RelativePath)
-		analyzeHooksMap(service.Hooks, serviceSegment, servicePath)
+		servicePath := filepath.Join(azdProject.Root, service.RelativePath)
+		analyzeHooksMap(templateCtx, service.Hooks, serviceSegment, servicePath, hookRules)
 	}
 
 	if hasServiceHooks {
@@ -239,32 +327,20 @@ func analyzeHooks(ctx AnalysisContext, template *templates.Template, root *Segme
 	return nil
 }
 
-func hasFilePattern(path string, pattern string) bool {
-	matches := []string{}
-
-	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// hasFilePattern returns true when a file within the directory of the file index matches the file name pattern.
+// The directory is relative to the template root, "." for the template root.
+func hasFilePattern(files []string, dir string, pattern string) bool {
+	for _, file := range files {
+		if dir != "." && !strings.HasPrefix(file, dir+"/") {
+			continue
 		}
 
-		if !entry.IsDir() {
-			matched, err := filepath.Match(pattern, entry.Name())
-			if err != nil {
-				return err
-			}
-			if matched {
-				matches = append(matches, path)
-			}
+		if matched, _ := filepath.Match(pattern, filepath.Base(file)); matched {
+			return true
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return false
 	}
 
-	return len(matches) > 0
+	return false
 }
 
 func hasDir(root string, dirName string) bool {
@@ -288,13 +364,14 @@ func hasHostType(azdProject project.Project, hostType string) bool {
 	return false
 }
 
-func hasLanguage(azdProject project.Project, languageSet []string) bool {
+// hasLanguage returns true when a service declares a language of the language family, see languageFamilies.
+func hasLanguage(azdProject project.Project, family string) bool {
 	if len(azdProject.Services) == 0 {
 		return false
 	}
 
 	for _, service := range azdProject.Services {
-		if slices.Contains(languageSet, service.Language) {
+		if languageFamilies[strings.ToLower(service.Language)] == family {
 			return true
 		}
 	}
@@ -302,75 +379,37 @@ func hasLanguage(azdProject project.Project, languageSet []string) bool {
 	return false
 }
 
-func analyzeHooksMap(hooks map[string]project.Hook, root *Segment, filePath string) {
+func analyzeHooksMap(templateCtx *TemplateContext, hooks map[string]project.HookSequence, root *Segment, filePath string, hookRules []*Rule) {
 	totalLocCount := 0
 
-	for hookName, hook := range hooks {
+	for hookName, hookSequence := range hooks {
 		locCount := 0
+		analyzed := false
 
 		hookSegment := NewSegment()
 		root.Segments[hookName] = hookSegment
+		hookSegment.Insights["usesHookList"] = NewInsight(BoolInsight, hookSequence.IsList)
 
-		hookRun := hook.Run
-		if hookRun == "" && hook.Posix != nil {
-			hookRun = hook.Posix.Run
-		}
-		if hookRun == "" && hook.Windows != nil {
-			hookRun = hook.Windows.Run
-		}
-		if hookRun == "" {
-			hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("%s hook missing run command", hookName))
-			continue
-		}
-
-		hasWindowsScript := hook.Windows != nil && hook.Windows.Run != ""
-		hasPosixScript := hook.Posix != nil && hook.Posix.Run != ""
-
-		usesOsVariantScripts := hasWindowsScript && hasPosixScript
-		hookSegment.Insights["usesOsVariantScripts"] = NewInsight(BoolInsight, usesOsVariantScripts)
-
-		var hookScript string
-		scriptPath := filepath.Join(filePath, hookRun)
-		_, err := os.Stat(scriptPath)
+		// Single hooks are analyzed within the hook segment, list entries within a segment per entry
+		for i, hook := range hookSequence.Entries {
+			entryName := hookName
+			entrySegment := hookSegment
 
-		// Inline script
-		if err != nil {
-			hookSegment.Insights["usesInlineScript"] = NewInsight(BoolInsight, true)
-			hookScript = hookRun
-		} else { // File script
-			hookSegment.Insights["usesInlineScript"] = NewInsight(BoolInsight, false)
-			hookBytes, err := os.ReadFile(scriptPath)
-			if err != nil {
-				hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("Failed reading hook file '%s': %v", scriptPath, err))
+			if hookSequence.IsList {
+				entryName = fmt.Sprintf("%s[%d]", hookName, i)
+				entrySegment = NewSegment()
+				hookSegment.Segments[strconv.Itoa(i)] = entrySegment
 			}
-			hookScript = string(hookBytes)
-		}
-
-		allScripts := map[string]string{
-			"script": hookScript,
-		}
-
-		embeddedScripts := scriptRegex.FindAllString(hookScript, -1)
-		for _, scriptPath := range embeddedScripts {
-			embeddedScriptPath := filepath.Join(filePath, scriptPath)
-			scriptBytes, err := os.ReadFile(embeddedScriptPath)
-			if err != nil {
-				hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("Failed reading embedded script '%s': %v", embeddedScriptPath, err))
-			} else {
-				hookScript := string(scriptBytes)
-				allScripts[scriptPath] = hookScript
-			}
-		}
 
-		for heuristicKey, heuristic := range heuristicMap {
-			for key, script := range allScripts {
-				hookSegment.Data[key] = script
-				hookSegment.Insights[heuristicKey] = NewInsight(BoolInsight, heuristic.MatchString(script))
+			entryLocCount, ok := analyzeHook(templateCtx, entryName, hook, entrySegment, filePath, hookRules)
+			if ok {
+				analyzed = true
+				locCount += entryLocCount
 			}
 		}
 
-		for _, script := range allScripts {
-			locCount += len(strings.Split(script, "\n"))
+		if !analyzed {
+			continue
 		}
 
 		hookSegment.Insights["hooks-loc"] = NewInsight(NumberInsight, locCount)
@@ -399,4 +438,110 @@ func analyzeHooksMap(hooks map[string]project.Hook, root *Segment, filePath stri
 	root.Insights["hooks-loc"] = NewInsight(NumberInsight, totalLocCount)
 }
 
+// analyzeHook analyzes a single hook definition and returns the lines of code of the hook scripts.
+// It returns false when the hook has no run command.
+func analyzeHook(templateCtx *TemplateContext, hookName string, hook project.Hook, hookSegment *Segment, filePath string, hookRules []*Rule) (int, bool) {
+	locCount := 0
+
+	hookRun := hook.Run
+	if hookRun == "" && hook.Posix != nil {
+		hookRun = hook.Posix.Run
+	}
+	if hookRun == "" && hook.Windows != nil {
+		hookRun = hook.Windows.Run
+	}
+	if hookRun == "" {
+		hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("%s: %s hook missing run command", hook.Position, hookName))
+		return 0, false
+	}
+
+	hasWindowsScript := hook.Windows != nil && hook.Windows.Run != ""
+	hasPosixScript := hook.Posix != nil && hook.Posix.Run != ""
+
+	usesOsVariantScripts := hasWindowsScript && hasPosixScript
+	hookSegment.Insights["usesOsVariantScripts"] = NewInsight(BoolInsight, usesOsVariantScripts)
+
+	usesContinueOnError := hook.ContinueOnError ||
+		(hook.Posix != nil && hook.Posix.ContinueOnError) ||
+		(hook.Windows != nil && hook.Windows.ContinueOnError)
+	usesInteractive := hook.Interactive ||
+		(hook.Posix != nil && hook.Posix.Interactive) ||
+		(hook.Windows != nil && hook.Windows.Interactive)
+
+	hookSegment.Insights["usesContinueOnError"] = NewInsight(BoolInsight, usesContinueOnError)
+	hookSegment.Insights["usesInteractive"] = NewInsight(BoolInsight, usesInteractive)
+
+	var hookScript string
+	scriptPath := filepath.Join(filePath, hookRun)
+	_, err := os.Stat(scriptPath)
+
+	// Inline script
+	if err != nil {
+		hookSegment.Insights["usesInlineScript"] = NewInsight(BoolInsight, true)
+		hookScript = hookRun
+	} else { // File script
+		hookSegment.Insights["usesInlineScript"] = NewInsight(BoolInsight, false)
+		hookBytes, err := templateCtx.ReadFile(scriptPath)
+		if err != nil {
+			hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("%s: Failed reading hook file '%s': %v", hook.Position, scriptPath, err))
+		}
+		hookScript = string(hookBytes)
+	}
+
+	allScripts := map[string]string{
+		"script": hookScript,
+	}
+
+	embeddedScripts := scriptRegex.FindAllString(hookScript, -1)
+	for _, scriptPath := range embeddedScripts {
+		embeddedScriptPath := filepath.Join(filePath, scriptPath)
+		scriptBytes, err := templateCtx.ReadFile(embeddedScriptPath)
+		if err != nil {
+			hookSegment.Errors = append(hookSegment.Errors, fmt.Sprintf("%s: Failed reading embedded script '%s': %v", hook.Position, embeddedScriptPath, err))
+		} else {
+			hookScript := string(scriptBytes)
+			allScripts[scriptPath] = hookScript
+		}
+	}
+
+	for key, script := range allScripts {
+		hookSegment.Data[key] = script
+	}
+
+	analyzeHookRules(hookRules, allScripts, hookSegment)
+
+	for _, script := range allScripts {
+		locCount += len(strings.Split(script, "\n"))
+	}
+
+	return locCount, true
+}
+
 var scriptRegex = regexp.MustCompile(`([a-zA-Z]:[\\/]|[\\/])?((?:[a-zA-Z0-9_\-\.]+[\\/])*[a-zA-Z0-9_\-\.]+\.(sh|ps1))`)
+
+// newSchemaSegment records the azure.yaml schema violations with a count per violation kind.
+func newSchemaSegment(violations []*project.SchemaViolation) *Segment {
+	schemaSegment := NewSegment()
+	schemaSegment.Data["schemaVersion"] = project.SchemaVersion
+	schemaSegment.Data["violations"] = violations
+
+	kindCounts := map[project.ViolationKind]int{}
+	for _, violation := range violations {
+		kindCounts[violation.Kind]++
+	}
+
+	kinds := []project.ViolationKind{
+		project.ViolationUnknownProperty,
+		project.ViolationMissingProperty,
+		project.ViolationInvalidType,
+		project.ViolationInvalidValue,
+	}
+
+	for _, kind := range kinds {
+		schemaSegment.Insights[fmt.Sprintf("schema-%s", kind)] = NewInsight(NumberInsight, kindCounts[kind])
+	}
+
+	schemaSegment.Insights["schemaViolationCount"] = NewInsight(NumberInsight, len(violations))
+
+	return schemaSegment
+}
@@ -0,0 +1,293 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AnalyzerInfo describes an analyzer and the results it produces.
+type AnalyzerInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Dependencies are the names of the analyzers whose results the analyzer consumes. Dependencies run first.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Segments are the keys of the top level segments the analyzer adds to the analysis.
+	Segments []string `json:"segments,omitempty"`
+	// Insights are the keys of the insights the analyzer produces within its segments,
+	// * matches insights named after detected values.
+	Insights []string `json:"insights,omitempty"`
+}
+
+// Analyzer analyzes one aspect of a template and records its results in the template analysis.
+type Analyzer interface {
+	Info() AnalyzerInfo
+	Analyze(ctx context.Context, templateCtx *TemplateContext, analysis *Segment) error
+}
+
+// AnalyzerFunc analyzes a template and records its results in the template analysis.
+type AnalyzerFunc func(ctx context.Context, templateCtx *TemplateContext, analysis *Segment) error
+
+type funcAnalyzer struct {
+	info        AnalyzerInfo
+	analyzeFunc AnalyzerFunc
+}
+
+// NewAnalyzer creates an analyzer from the analyzer description and analysis function.
+func NewAnalyzer(info AnalyzerInfo, analyzeFunc AnalyzerFunc) Analyzer {
+	return &funcAnalyzer{
+		info:        info,
+		analyzeFunc: analyzeFunc,
+	}
+}
+
+func (a *funcAnalyzer) Info() AnalyzerInfo {
+	return a.info
+}
+
+func (a *funcAnalyzer) Analyze(ctx context.Context, templateCtx *TemplateContext, analysis *Segment) error {
+	return a.analyzeFunc(ctx, templateCtx, analysis)
+}
+
+// Registry is a set of analyzers by name.
+type Registry struct {
+	analyzers map[string]Analyzer
+	// names is the registration order, used to order analyzers that do not depend on each other
+	names []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		analyzers: map[string]Analyzer{},
+		names:     []string{},
+	}
+}
+
+// Register adds the analyzer to the registry. Analyzer names must be unique.
+func (r *Registry) Register(analyzer Analyzer) error {
+	name := analyzer.Info().Name
+	if name == "" {
+		return fmt.Errorf("analyzer name is required")
+	}
+
+	if _, has := r.analyzers[name]; has {
+		return fmt.Errorf("analyzer '%s' is already registered", name)
+	}
+
+	r.analyzers[name] = analyzer
+	r.names = append(r.names, name)
+
+	return nil
+}
+
+// Get returns the analyzer with the specified name.
+func (r *Registry) Get(name string) (Analyzer, bool) {
+	analyzer, has := r.analyzers[name]
+	return analyzer, has
+}
+
+// Names returns the names of the registered analyzers in registration order.
+func (r *Registry) Names() []string {
+	return slices.Clone(r.names)
+}
+
+// Analyzers returns the registered analyzers in registration order.
+func (r *Registry) Analyzers() []Analyzer {
+	analyzers := []Analyzer{}
+	for _, name := range r.names {
+		analyzers = append(analyzers, r.analyzers[name])
+	}
+
+	return analyzers
+}
+
+// Resolve returns the analyzers to run in dependency order. When include is empty all registered analyzers
+// are selected, otherwise the included analyzers and their dependencies. Excluded analyzers are removed and
+// must not be a dependency of a selected analyzer.
+func (r *Registry) Resolve(include []string, exclude []string) ([]Analyzer, error) {
+	for _, name := range slices.Concat(include, exclude) {
+		if _, has := r.analyzers[name]; !has {
+			return nil, fmt.Errorf("unknown analyzer '%s', available analyzers: %s", name, strings.Join(r.names, ", "))
+		}
+	}
+
+	selected := map[string]bool{}
+
+	var selectWithDependencies func(name string) error
+	selectWithDependencies = func(name string) error {
+		if selected[name] {
+			return nil
+		}
+
+		selected[name] = true
+
+		for _, dependency := range r.analyzers[name].Info().Dependencies {
+			if _, has := r.analyzers[dependency]; !has {
+				return fmt.Errorf("analyzer '%s' depends on unknown analyzer '%s'", name, dependency)
+			}
+
+			if err := selectWithDependencies(dependency); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	roots := include
+	if len(roots) == 0 {
+		roots = r.names
+	}
+
+	for _, name := range roots {
+		if slices.Contains(exclude, name) {
+			continue
+		}
+
+		if err := selectWithDependencies(name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range exclude {
+		if !selected[name] {
+			continue
+		}
+
+		for _, dependent := range r.names {
+			if selected[dependent] && slices.Contains(r.analyzers[dependent].Info().Dependencies, name) {
+				return nil, fmt.Errorf("analyzer '%s' cannot be skipped, analyzer '%s' depends on it", name, dependent)
+			}
+		}
+	}
+
+	return r.sort(selected)
+}
+
+// sort orders the selected analyzers so every analyzer runs after its dependencies.
+// Analyzers without dependencies between them keep the registration order.
+func (r *Registry) sort(selected map[string]bool) ([]Analyzer, error) {
+	sorted := []Analyzer{}
+	done := map[string]bool{}
+
+	for len(done) < len(selected) {
+		progressed := false
+
+		for _, name := range r.names {
+			if !selected[name] || done[name] {
+				continue
+			}
+
+			ready := !slices.ContainsFunc(r.analyzers[name].Info().Dependencies, func(dependency string) bool {
+				return !done[dependency]
+			})
+
+			if ready {
+				done[name] = true
+				sorted = append(sorted, r.analyzers[name])
+				progressed = true
+
+				// Restart from the first registered analyzer to preserve the registration order
+				break
+			}
+		}
+
+		if !progressed {
+			cycle := []string{}
+			for _, name := range r.names {
+				if selected[name] && !done[name] {
+					cycle = append(cycle, name)
+				}
+			}
+
+			return nil, fmt.Errorf("analyzers have circular dependencies: %s", strings.Join(cycle, ", "))
+		}
+	}
+
+	return sorted, nil
+}
+
+// DefaultRegistry contains the built-in analyzers.
+var DefaultRegistry = func() *Registry {
+	registry := NewRegistry()
+
+	for _, analyzer := range builtinAnalyzers {
+		if err := registry.Register(analyzer); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}()
+
+var builtinAnalyzers = []Analyzer{
+	NewAnalyzer(AnalyzerInfo{
+		Name:        "hooks",
+		Description: "Project and service hooks, their scripts and the tools they use",
+		Segments:    []string{"hooks"},
+		// Hook script rules of the rule set record additional insights in every hook segment
+		Insights: []string{
+			"hasProjectHooks", "hasServiceHooks", "usesHookList", "usesOsVariantScripts", "usesContinueOnError",
+			"usesInteractive", "usesInlineScript", "hooks-loc", "type-*",
+		},
+	}, analyzeHooks),
+	NewAnalyzer(AnalyzerInfo{
+		Name:         "project",
+		Description:  "azure.yaml configuration, schema conformance, hosts, languages and .NET Aspire app hosts",
+		Dependencies: []string{"hooks"},
+		Segments:     []string{"project", "schema"},
+		Insights: []string{
+			"hasHooks", "hasWorkflows", "hasMetadata", "hasServices", "serviceCount", "usesAzureYml", "isNestedProject",
+			"hasMultipleProjects", "projectFileCount", "hasInfraConfig", "hasPipelineConfig", "hasRemoteState",
+			"hasRequiredVersions", "hasPlatform", "hasResources", "infraTerraformProvider", "resourceCount",
+			"isSchemaValid", "schemaViolationCount", "schema-*", "usesDockerConfig", "usesK8sConfig", "usesServiceEnv",
+			"host-*", "lang-*", "isAspire", "aspireAppHostCount", "aspireResourceCount", "aspireServiceCount", "aspire-*",
+		},
+	}, analyzeProject),
+	NewAnalyzer(AnalyzerInfo{
+		Name:         "services",
+		Description:  "Service project paths and declared languages",
+		Dependencies: []string{"project"},
+		Segments:     []string{"serviceChecks"},
+		Insights: []string{
+			"pathExists", "languageMatches", "hasMissingServicePath", "hasLanguageMismatch", "missingServicePathCount",
+			"languageMismatchCount",
+		},
+	}, analyzeServices),
+	NewAnalyzer(AnalyzerInfo{
+		Name:         "docker",
+		Description:  "Dockerfiles of containerapp and aks services",
+		Dependencies: []string{"project"},
+		Segments:     []string{"docker"},
+		Insights: []string{
+			"containerServiceCount", "dockerfileCount", "hasDockerfile", "dockerfileParseError", "isMultiStage",
+			"usesNonRootUser", "usesLatestTag", "exposesPorts", "stageCount",
+		},
+	}, analyzeDocker),
+	NewAnalyzer(AnalyzerInfo{
+		Name:        "template",
+		Description: "Catalog metadata and repository layout",
+		Segments:    []string{"template"},
+		Insights: []string{
+			"tagCount", "isCommunity", "isMsft", "hasAzureYaml", "hasInfra", "hasGithub", "hasAzdo", "hasDevcontainer",
+			"infraBicep", "infraTerraform",
+		},
+	}, analyzeTemplate),
+	NewAnalyzer(AnalyzerInfo{
+		Name:        rulesAnalyzer,
+		Description: "File and azure.yaml heuristic rules, hook script rules are evaluated by the hooks analyzer",
+		// Segments and insights are declared by the rule set, rules may record insights in the segments
+		// of the template and project analyzers
+		Dependencies: []string{"template", "project"},
+	}, analyzeRules),
+	NewAnalyzer(AnalyzerInfo{
+		Name:         "catalog",
+		Description:  "Catalog tags compared with the detected IaC, languages and hosts",
+		Dependencies: []string{"template", "project"},
+		Segments:     []string{"catalog"},
+		Insights: []string{
+			"hasCatalogClaims", "hasDiscrepancies", "discrepancyCount", "iacMismatch", "languageMismatch", "hostMismatch",
+		},
+	}, analyzeCatalog),
+}
@@ -0,0 +1,272 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+func testAnalyzer(name string, dependencies ...string) Analyzer {
+	return NewAnalyzer(AnalyzerInfo{Name: name, Dependencies: dependencies},
+		func(ctx context.Context, templateCtx *TemplateContext, analysis *Segment) error {
+			return nil
+		})
+}
+
+func analyzerNames(analyzers []Analyzer) []string {
+	names := []string{}
+	for _, analyzer := range analyzers {
+		names = append(names, analyzer.Info().Name)
+	}
+
+	return names
+}
+
+func TestRegistryResolve(t *testing.T) {
+	registry := NewRegistry()
+	for _, analyzer := range []Analyzer{
+		testAnalyzer("catalog", "template", "project"),
+		testAnalyzer("project", "hooks"),
+		testAnalyzer("hooks"),
+		testAnalyzer("template"),
+	} {
+		if err := registry.Register(analyzer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := registry.Register(testAnalyzer("hooks")); err == nil {
+		t.Error("expected duplicate analyzer registration to fail")
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+		err      string
+	}{
+		{name: "all", expected: []string{"hooks", "project", "template", "catalog"}},
+		{name: "include dependencies", include: []string{"project"}, expected: []string{"hooks", "project"}},
+		{name: "skip", exclude: []string{"catalog", "template"}, expected: []string{"hooks", "project"}},
+		{name: "skip dependency", exclude: []string{"hooks"}, err: "analyzer 'project' depends on it"},
+		{name: "unknown", include: []string{"docs"}, err: "unknown analyzer 'docs'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analyzers, err := registry.Resolve(test.include, test.exclude)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing '%s', got %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := analyzerNames(analyzers); !slices.Equal(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestRegistryResolveCycle(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(testAnalyzer("a", "b"))
+	registry.Register(testAnalyzer("b", "a"))
+
+	if _, err := registry.Resolve(nil, nil); err == nil || !strings.Contains(err.Error(), "circular") {
+		t.Errorf("expected circular dependency error, got %v", err)
+	}
+}
+
+const analyzerInfoRules = `
+rules:
+  - insight: usesAzCli
+    hookScript: 'az\s'
+  - insight: hasReadme
+    fileGlob: README.md
+  - insight: bicepModuleCount
+    type: number
+    segment: template
+    fileGlob: 'infra/**/*.bicep'
+`
+
+// writeAnalyzerFixture writes a template producing the insights of every built-in analyzer.
+func writeAnalyzerFixture(t testing.TB, templatePath string) {
+	writeTestFiles(t, templatePath, map[string]string{
+		"azure.yaml": `name: fixture
+metadata:
+  template: fixture@0.0.1
+hooks:
+  preprovision:
+    posix:
+      shell: sh
+      run: ./scripts/setup.sh
+    windows:
+      shell: pwsh
+      run: ./scripts/setup.ps1
+  postprovision:
+    - shell: sh
+      run: az account show
+      continueOnError: true
+services:
+  api:
+    project: ./src/api
+    language: python
+    host: containerapp
+    hooks:
+      prebuild:
+        shell: sh
+        run: echo build
+  web:
+    project: ./src/web
+    language: python
+    host: appservice
+  missing:
+    project: ./src/missing
+    language: python
+    host: function
+  apphost:
+    project: ./src/apphost
+    language: dotnet
+    host: containerapp
+`,
+		"README.md":                       "# fixture\n",
+		"scripts/setup.sh":                "#!/bin/sh\nazd env get-values\n",
+		"scripts/setup.ps1":               "azd env get-values\n",
+		"src/api/Dockerfile":              "FROM python:3.12\nUSER app\nEXPOSE 8000\n",
+		"src/api/requirements.txt":        "fastapi\n",
+		"src/web/package.json":            "{}\n",
+		"src/apphost/AppHost.csproj":      "<Project Sdk=\"Microsoft.NET.Sdk\">\n  <Sdk Name=\"Aspire.AppHost.Sdk\" Version=\"9.0.0\" />\n</Project>\n",
+		"src/apphost/Program.cs":          "builder.AddRedis(\"cache\");\nbuilder.AddProject<Projects.Orders>(\"orders\");\n",
+		"infra/main.bicep":                "module app './app.bicep' = {}\n",
+		"infra/app.bicep":                 "",
+		".github/workflows/azure-dev.yml": "",
+		".azdo/pipelines/azure-dev.yml":   "",
+		".devcontainer/devcontainer.json": "{}\n",
+	})
+}
+
+// analysisKeys returns the segment path qualified keys of the insights within the segment and its child segments.
+func analysisKeys(path string, segment *Segment, keys map[string]string) {
+	for key := range segment.Insights {
+		keys[fmt.Sprintf("%s/%s", path, key)] = key
+	}
+
+	for segmentKey, child := range segment.Segments {
+		analysisKeys(fmt.Sprintf("%s/%s", path, segmentKey), child, keys)
+	}
+}
+
+// TestAnalyzerInfo runs every built-in analyzer on a fixture and compares the segments and insights it produces
+// with the segments and insights declared by the analyzer.
+func TestAnalyzerInfo(t *testing.T) {
+	rules := mustParseRules(t, analyzerInfoRules)
+
+	ruleInsights := map[string][]string{}
+	ruleSegments := []string{}
+	for _, rule := range rules.Rules {
+		if rule.HookScript != "" {
+			ruleInsights["hooks"] = append(ruleInsights["hooks"], rule.Insight)
+			continue
+		}
+
+		ruleInsights[rulesAnalyzer] = append(ruleInsights[rulesAnalyzer], rule.Insight)
+		ruleSegments = append(ruleSegments, rule.Segment)
+	}
+
+	template := &templates.Template{
+		Source: "https://github.com/contoso/fixture",
+		Tags:   []string{"msft", "bicep", "python", "functions"},
+	}
+
+	for _, name := range DefaultRegistry.Names() {
+		t.Run(name, func(t *testing.T) {
+			analyzers, err := DefaultRegistry.Resolve([]string{name}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir(), Rules: rules}, template)
+			templatePath, err := templateCtx.Path()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writeAnalyzerFixture(t, templatePath)
+
+			root := NewSegment()
+			before := map[string]string{}
+			beforeSegments := []string{}
+
+			for _, analyzer := range analyzers {
+				if analyzer.Info().Name == name {
+					analysisKeys("", root, before)
+					beforeSegments = slices.Collect(maps.Keys(root.Segments))
+				}
+
+				if err := analyzer.Analyze(context.Background(), templateCtx, root); err != nil {
+					t.Fatalf("analyzer '%s' failed: %v", analyzer.Info().Name, err)
+				}
+			}
+
+			info, _ := DefaultRegistry.Get(name)
+			insights := slices.Concat(info.Info().Insights, ruleInsights[name])
+			segments := info.Info().Segments
+			if name == rulesAnalyzer {
+				segments = ruleSegments
+			}
+
+			after := map[string]string{}
+			analysisKeys("", root, after)
+
+			produced := map[string]bool{}
+			for path, key := range after {
+				if _, has := before[path]; !has {
+					produced[key] = true
+				}
+			}
+
+			for key := range produced {
+				if !slices.ContainsFunc(insights, func(pattern string) bool { return matchInsight(pattern, key) }) {
+					t.Errorf("insight '%s' is not declared", key)
+				}
+			}
+
+			for _, pattern := range insights {
+				if !slices.ContainsFunc(slices.Collect(maps.Keys(produced)), func(key string) bool { return matchInsight(pattern, key) }) {
+					t.Errorf("declared insight '%s' was not produced", pattern)
+				}
+			}
+
+			for segmentKey := range root.Segments {
+				if !slices.Contains(beforeSegments, segmentKey) && !slices.Contains(segments, segmentKey) {
+					t.Errorf("segment '%s' is not declared", segmentKey)
+				}
+			}
+
+			for _, segmentKey := range segments {
+				if _, has := root.Segments[segmentKey]; !has {
+					t.Errorf("declared segment '%s' was not produced", segmentKey)
+				}
+			}
+		})
+	}
+}
+
+func matchInsight(pattern string, key string) bool {
+	matched, err := filepath.Match(pattern, key)
+	return err == nil && matched
+}
@@ -0,0 +1,87 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/wbreza/azd-template-analysis/aspire"
+	"github.com/wbreza/azd-template-analysis/project"
+)
+
+// analyzeAspire records the .NET Aspire app hosts of the project and returns the services azd deploys.
+// Services of an app host are declared in the app host program instead of azure.yaml, so the app host
+// service is replaced by the service resources of the app host, which azd deploys to container apps.
+func analyzeAspire(
+	ctx context.Context,
+	templateCtx *TemplateContext,
+	azdProject *project.Project,
+	projectSegment *Segment,
+) map[string]project.Service {
+	services := maps.Clone(azdProject.Services)
+	if services == nil {
+		services = map[string]project.Service{}
+	}
+
+	appHostCount := 0
+	resources := []*aspire.Resource{}
+	kinds := []string{}
+
+	fsys := templateFileSystem{ctx: ctx, templateCtx: templateCtx}
+
+	// Services are visited in name order so resources and errors are reported in a stable order
+	serviceNames := slices.Sorted(maps.Keys(azdProject.Services))
+
+	for _, serviceName := range serviceNames {
+		service := azdProject.Services[serviceName]
+		projectFile, isAppHost := aspire.FindAppHostFS(fsys, filepath.Join(azdProject.Root, service.RelativePath))
+		if !isAppHost {
+			continue
+		}
+
+		appHost, err := aspire.LoadFS(fsys, projectFile)
+		if err != nil {
+			projectSegment.Errors = append(projectSegment.Errors, err.Error())
+			continue
+		}
+
+		appHostCount++
+		resources = append(resources, appHost.Resources...)
+		kinds = append(kinds, appHost.Kinds()...)
+
+		delete(services, serviceName)
+		for _, resource := range appHost.Services() {
+			services[resource.Name] = project.Service{
+				RelativePath: service.RelativePath,
+				Host:         "containerapp",
+				Language:     resource.Language(),
+			}
+		}
+	}
+
+	projectSegment.Insights["isAspire"] = NewInsight(BoolInsight, appHostCount > 0)
+
+	if appHostCount == 0 {
+		return services
+	}
+
+	serviceResourceCount := 0
+	for _, resource := range resources {
+		if resource.IsService() {
+			serviceResourceCount++
+		}
+	}
+
+	projectSegment.Data["aspireResources"] = resources
+	projectSegment.Insights["aspireAppHostCount"] = NewInsight(NumberInsight, appHostCount)
+	projectSegment.Insights["aspireResourceCount"] = NewInsight(NumberInsight, len(resources))
+	projectSegment.Insights["aspireServiceCount"] = NewInsight(NumberInsight, serviceResourceCount)
+
+	for _, kind := range kinds {
+		projectSegment.Insights[fmt.Sprintf("aspire-%s", kind)] = NewInsight(BoolInsight, true)
+	}
+
+	return services
+}
@@ -0,0 +1,165 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+// Discrepancy is a mismatch between the catalog metadata of a template and the facts detected from its repository.
+type Discrepancy struct {
+	Category string `json:"category"`
+	Value    string `json:"value"`
+	Claimed  bool   `json:"claimed"`
+	Detected bool   `json:"detected"`
+	Message  string `json:"message"`
+}
+
+// catalogCheck maps catalog tags to the insight detecting the same fact from the repository.
+type catalogCheck struct {
+	category string
+	value    string
+	insight  string
+	// infraPattern detects the fact from the files within the infra directory of the project instead of an insight
+	infraPattern string
+	tags         []string
+	// requiresProject is set when the fact can only be detected from azure.yaml
+	requiresProject bool
+}
+
+var catalogChecks = []catalogCheck{
+	{category: "iac", value: "bicep", infraPattern: "*.bicep", tags: []string{"bicep"}},
+	{category: "iac", value: "terraform", infraPattern: "*.tf", tags: []string{"terraform"}},
+	{category: "language", value: "dotnet", insight: "lang-dotnet", tags: []string{"dotnet", "dotnetcsharp", "csharp", "fsharp"}, requiresProject: true},
+	{category: "language", value: "java", insight: "lang-java", tags: []string{"java"}, requiresProject: true},
+	{category: "language", value: "javascript", insight: "lang-javascript", tags: []string{"javascript", "typescript", "nodejs"}, requiresProject: true},
+	{category: "language", value: "python", insight: "lang-python", tags: []string{"python"}, requiresProject: true},
+	{category: "host", value: "appservice", insight: "host-appservice", tags: []string{"appservice"}, requiresProject: true},
+	{category: "host", value: "containerapp", insight: "host-containerapp", tags: []string{"aca", "containerapps"}, requiresProject: true},
+	{category: "host", value: "function", insight: "host-function", tags: []string{"functions"}, requiresProject: true},
+	{category: "host", value: "springapp", insight: "host-springapp", tags: []string{"springapps", "azurespringapps"}, requiresProject: true},
+	{category: "host", value: "aks", insight: "host-aks", tags: []string{"aks"}, requiresProject: true},
+	{category: "host", value: "staticwebapp", insight: "host-staticwebapp", tags: []string{"swa"}, requiresProject: true},
+}
+
+// analyzeCatalog compares the catalog tags of the template with the facts detected by the template and project analyzers.
+// Both analyzers are declared as dependencies of the catalog analyzer. The IaC is detected from the infra directory
+// configured in azure.yaml, which is not necessarily the infra directory of the template root.
+func analyzeCatalog(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	catalogSegment := NewSegment()
+	root.Segments["catalog"] = catalogSegment
+
+	files, err := templateCtx.Files(ctx)
+	if err != nil {
+		return err
+	}
+
+	claims := catalogClaims(templateCtx.Template)
+	hasProject := HasSegment(root, "project")
+	infraPath := infraDir(templateCtx)
+	discrepancies := []Discrepancy{}
+	mismatches := map[string]bool{}
+	checked := false
+
+	for _, check := range catalogChecks {
+		if check.requiresProject && !hasProject {
+			continue
+		}
+
+		claimed := slices.ContainsFunc(check.tags, func(tag string) bool { return claims[tag] })
+		detected := HasInsightValue(root, check.insight, true)
+		if check.infraPattern != "" {
+			detected = infraPath != "" && hasFilePattern(files, infraPath, check.infraPattern)
+		}
+		checked = checked || claimed
+
+		if claimed == detected {
+			continue
+		}
+
+		message := fmt.Sprintf("catalog lists %s '%s' but it was not detected", check.category, check.value)
+		if detected {
+			message = fmt.Sprintf("%s '%s' detected but not listed in the catalog", check.category, check.value)
+		}
+
+		mismatches[check.category] = true
+		discrepancies = append(discrepancies, Discrepancy{
+			Category: check.category,
+			Value:    check.value,
+			Claimed:  claimed,
+			Detected: detected,
+			Message:  message,
+		})
+	}
+
+	catalogSegment.Data["discrepancies"] = discrepancies
+
+	catalogSegment.Insights["hasCatalogClaims"] = NewInsight(BoolInsight, checked)
+	catalogSegment.Insights["iacMismatch"] = NewInsight(BoolInsight, mismatches["iac"])
+	catalogSegment.Insights["languageMismatch"] = NewInsight(BoolInsight, mismatches["language"])
+	catalogSegment.Insights["hostMismatch"] = NewInsight(BoolInsight, mismatches["host"])
+	catalogSegment.Insights["hasDiscrepancies"] = NewInsight(BoolInsight, len(discrepancies) > 0)
+	catalogSegment.Insights["discrepancyCount"] = NewInsight(NumberInsight, len(discrepancies))
+
+	return nil
+}
+
+// infraDir returns the slash separated infra directory relative to the template root, the infra.path of azure.yaml
+// relative to the azure.yaml directory or the infra directory of the template when the template has no azure.yaml.
+// It returns an empty path when the infra directory is outside of the template.
+func infraDir(templateCtx *TemplateContext) string {
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		return ""
+	}
+
+	azdProject, err := templateCtx.Project()
+	if err != nil || azdProject == nil {
+		return "infra"
+	}
+
+	infraPath := "infra"
+	if azdProject.Infra != nil && azdProject.Infra.Path != "" {
+		infraPath = azdProject.Infra.Path
+	}
+
+	relativePath, err := filepath.Rel(templatePath, filepath.Join(azdProject.Root, infraPath))
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return ""
+	}
+
+	return filepath.ToSlash(relativePath)
+}
+
+// catalogClaims returns the lowercased tags, languages, frameworks, IaC and Azure services of the catalog entry.
+func catalogClaims(template *templates.Template) map[string]bool {
+	claims := map[string]bool{}
+
+	for _, values := range [][]string{template.Tags, template.Languages, template.Frameworks, template.IaC, template.AzureServices} {
+		for _, value := range values {
+			claims[strings.ToLower(value)] = true
+		}
+	}
+
+	return claims
+}
+
+// CatalogDiscrepancies returns the catalog discrepancies found for the template analysis.
+func CatalogDiscrepancies(analysis *Segment) []Discrepancy {
+	if analysis == nil {
+		return nil
+	}
+
+	catalogSegment, has := analysis.Segments["catalog"]
+	if !has {
+		return nil
+	}
+
+	discrepancies, _ := catalogSegment.Data["discrepancies"].([]Discrepancy)
+
+	return discrepancies
+}
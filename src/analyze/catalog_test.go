@@ -0,0 +1,102 @@
+package analyze
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+func TestAnalyzeCatalog(t *testing.T) {
+	tests := []struct {
+		name          string
+		tags          []string
+		files         map[string]string
+		iacMismatch   bool
+		discrepancies []string
+	}{
+		{
+			name: "tagged bicep with terraform",
+			tags: []string{"bicep"},
+			files: map[string]string{
+				"azure.yaml":    "name: app\ninfra:\n  provider: terraform\n",
+				"infra/main.tf": "",
+			},
+			iacMismatch:   true,
+			discrepancies: []string{"iac/bicep", "iac/terraform"},
+		},
+		{
+			name: "custom infra path",
+			tags: []string{"bicep"},
+			files: map[string]string{
+				"azure.yaml":              "name: app\ninfra:\n  path: deploy/bicep\n",
+				"deploy/bicep/main.bicep": "",
+				"infra/legacy/main.tf":    "",
+			},
+		},
+		{
+			name: "nested azure.yaml",
+			tags: []string{"terraform"},
+			files: map[string]string{
+				"src/app/azure.yaml":    "name: app\ninfra:\n  provider: terraform\n",
+				"src/app/infra/main.tf": "",
+			},
+		},
+		{
+			name: "infra path outside of the default",
+			tags: []string{"bicep"},
+			files: map[string]string{
+				"azure.yaml":       "name: app\ninfra:\n  path: deploy\n",
+				"infra/main.bicep": "",
+			},
+			iacMismatch:   true,
+			discrepancies: []string{"iac/bicep"},
+		},
+		{
+			name: "language alias",
+			tags: []string{"bicep", "javascript", "typescript", "appservice"},
+			files: map[string]string{
+				"azure.yaml":       "name: app\nservices:\n  web:\n    project: ./src/web\n    language: js\n    host: appservice\n  api:\n    project: ./src/api\n    language: typescript\n    host: appservice\n",
+				"infra/main.bicep": "",
+			},
+		},
+	}
+
+	analyzers, err := DefaultRegistry.Resolve([]string{"catalog"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := &templates.Template{Source: "https://github.com/contoso/app", Tags: test.tags}
+			templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir(), Analyzers: analyzers}, template)
+
+			templatePath, err := templateCtx.Path()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writeTestFiles(t, templatePath, test.files)
+
+			analysis, err := analyzeTemplateContext(context.Background(), templateCtx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if iacMismatch, _ := GetInsight[bool](analysis, "iacMismatch"); !slices.Equal(iacMismatch, []bool{test.iacMismatch}) {
+				t.Errorf("expected iacMismatch %v, got %v", test.iacMismatch, iacMismatch)
+			}
+
+			discrepancies := []string{}
+			for _, discrepancy := range CatalogDiscrepancies(analysis) {
+				discrepancies = append(discrepancies, discrepancy.Category+"/"+discrepancy.Value)
+			}
+
+			if !slices.Equal(discrepancies, test.discrepancies) && (len(discrepancies) > 0 || len(test.discrepancies) > 0) {
+				t.Errorf("expected discrepancies %v, got %v", test.discrepancies, discrepancies)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/wbreza/azd-template-analysis/project"
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+// ignoredDirs are not included in the file index of a template.
+var ignoredDirs = []string{".git", "node_modules", "bin", "obj"}
+
+// IOStats counts the file system work of a template analysis.
+type IOStats struct {
+	ProjectLoads int `json:"projectLoads"`
+	FileWalks    int `json:"fileWalks"`
+	FileReads    int `json:"fileReads"`
+}
+
+// TemplateContext is the analysis state of a single template shared by the analyzers of the template.
+// The project, file index and file contents are loaded on first use and cached for the other analyzers.
+type TemplateContext struct {
+	AnalysisContext
+	Template *templates.Template
+
+	mu sync.Mutex
+
+	path    string
+	pathErr error
+
+	projectLoaded bool
+	project       *project.Project
+	projectErr    error
+
+	files    []string
+	filesErr error
+
+	contents map[string][]byte
+	stats    IOStats
+}
+
+func NewTemplateContext(analysisCtx AnalysisContext, template *templates.Template) *TemplateContext {
+	path, err := analysisCtx.TemplatePath(template)
+
+	return &TemplateContext{
+		AnalysisContext: analysisCtx,
+		Template:        template,
+		path:            path,
+		pathErr:         err,
+		contents:        map[string][]byte{},
+	}
+}
+
+// Path returns the directory of the template clone.
+func (t *TemplateContext) Path() (string, error) {
+	return t.path, t.pathErr
+}
+
+// Project returns the azure.yaml project of the template, loaded on first use.
+func (t *TemplateContext) Project() (*project.Project, error) {
+	if t.pathErr != nil {
+		return nil, t.pathErr
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.projectLoaded {
+		t.project, t.projectErr = project.Load(t.path)
+		t.projectLoaded = true
+		t.stats.ProjectLoads++
+	}
+
+	return t.project, t.projectErr
+}
+
+// Files returns the slash separated paths of the template files relative to the template root,
+// indexed on first use. Dependency, build output and .git directories are not indexed.
+func (t *TemplateContext) Files(ctx context.Context) ([]string, error) {
+	if t.pathErr != nil {
+		return nil, t.pathErr
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.files == nil && t.filesErr == nil {
+		t.files, t.filesErr = indexFiles(ctx, t.path)
+		t.stats.FileWalks++
+	}
+
+	return t.files, t.filesErr
+}
+
+// DirFiles returns the paths of the indexed files directly within the directory of the template.
+func (t *TemplateContext) DirFiles(ctx context.Context, dir string) ([]string, error) {
+	files, err := t.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relativeDir, err := filepath.Rel(t.path, dir)
+	if err != nil || relativeDir == ".." || strings.HasPrefix(relativeDir, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("directory '%s' is outside of the template", dir)
+	}
+
+	relativeDir = filepath.ToSlash(relativeDir)
+	dirFiles := []string{}
+
+	for _, file := range files {
+		if path.Dir(file) == relativeDir {
+			dirFiles = append(dirFiles, filepath.Join(t.path, filepath.FromSlash(file)))
+		}
+	}
+
+	return dirFiles, nil
+}
+
+// ReadFile returns the contents of the file at the path, read on first use.
+func (t *TemplateContext) ReadFile(path string) ([]byte, error) {
+	path = filepath.Clean(path)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if contents, has := t.contents[path]; has {
+		return contents, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	t.stats.FileReads++
+	if err != nil {
+		return nil, err
+	}
+
+	t.contents[path] = contents
+
+	return contents, nil
+}
+
+// templateFileSystem serves the cached files of the template context to packages reading files by path.
+type templateFileSystem struct {
+	ctx         context.Context
+	templateCtx *TemplateContext
+}
+
+func (f templateFileSystem) Files(dir string) ([]string, error) {
+	return f.templateCtx.DirFiles(f.ctx, dir)
+}
+
+func (f templateFileSystem) ReadFile(path string) ([]byte, error) {
+	return f.templateCtx.ReadFile(path)
+}
+
+// Stats returns the file system work of the template analysis so far.
+func (t *TemplateContext) Stats() IOStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats
+}
+
+func indexFiles(ctx context.Context, templatePath string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(templatePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != templatePath && slices.Contains(ignoredDirs, entry.Name()) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(templatePath, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(relativePath))
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
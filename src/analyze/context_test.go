@@ -0,0 +1,238 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+const benchmarkRules = `
+rules:
+  - insight: bicepModuleCount
+    type: number
+    segment: template
+    fileGlob: 'infra/**/*.bicep'
+  - insight: usesManagedIdentity
+    fileContent:
+      glob: 'infra/**/*.bicep'
+      pattern: 'Microsoft\.ManagedIdentity'
+  - insight: usesRemoteBuild
+    segment: project
+    projectPath: services.*.docker.remoteBuild
+    equals: true
+`
+
+// writeSyntheticTemplate writes a template with hooks, container services and infra modules.
+func writeSyntheticTemplate(t testing.TB, templatePath string, sourceFiles int) {
+	files := map[string]string{
+		"azure.yaml": `name: synthetic
+hooks:
+  preprovision:
+    run: ./scripts/setup.sh
+    shell: sh
+  postprovision:
+    - run: az login && azd env get-values
+      shell: sh
+    - run: ./scripts/seed.sh
+      shell: sh
+services:
+  api:
+    project: ./src/api
+    language: python
+    host: containerapp
+    docker:
+      remoteBuild: true
+  web:
+    project: ./src/web
+    language: js
+    host: containerapp
+`,
+		"scripts/setup.sh":          "#!/bin/sh\naz account show\n./scripts/seed.sh\n",
+		"scripts/seed.sh":           "#!/bin/sh\nazd env get-values\n",
+		"src/api/Dockerfile":        "FROM python:3.12 AS build\nFROM python:3.12-slim\nUSER app\nEXPOSE 8000\n",
+		"src/api/requirements.txt":  "fastapi\n",
+		"src/web/Dockerfile":        "FROM node:20\nEXPOSE 3000\n",
+		"src/web/package.json":      "{}\n",
+		"infra/main.bicep":          "module identity './core/identity.bicep' = {}\n",
+		"infra/core/identity.bicep": "resource identity 'Microsoft.ManagedIdentity/userAssignedIdentities@2023-01-31' = {}\n",
+	}
+
+	for i := range sourceFiles {
+		files[fmt.Sprintf("src/web/components/component%d.js", i)] = "export default {}\n"
+	}
+
+	writeTestFiles(t, templatePath, files)
+}
+
+func TestTemplateContextCachesLoadedState(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/synthetic"}
+	analysisCtx := AnalysisContext{WorkingDirectory: t.TempDir()}
+	templateCtx := NewTemplateContext(analysisCtx, template)
+
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeSyntheticTemplate(t, templatePath, 5)
+
+	if _, err := analyzeTemplateContext(context.Background(), templateCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := templateCtx.Stats()
+	if stats.ProjectLoads != 1 || stats.FileWalks != 1 {
+		t.Errorf("expected a single project load and file walk, got %+v", stats)
+	}
+
+	// Scripts and Dockerfiles are read once although several hooks and analyzers reference them
+	reads := stats.FileReads
+	if _, err := templateCtx.ReadFile(filepath.Join(templatePath, "scripts", "seed.sh")); err != nil {
+		t.Fatal(err)
+	}
+
+	if templateCtx.Stats().FileReads != reads {
+		t.Errorf("expected cached file contents, got %d reads after %d", templateCtx.Stats().FileReads, reads)
+	}
+}
+
+func TestTemplateContextServesAppHostFiles(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/apphost"}
+	templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir()}, template)
+
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, templatePath, map[string]string{
+		"azure.yaml":                 "name: apphost\nservices:\n  app:\n    project: ./src/AppHost\n    language: dotnet\n    host: containerapp\n",
+		"src/AppHost/AppHost.csproj": "<Project Sdk=\"Microsoft.NET.Sdk\">\n  <Sdk Name=\"Aspire.AppHost.Sdk\" Version=\"9.0.0\" />\n</Project>\n",
+		"src/AppHost/Program.cs":     "builder.AddProject<Projects.Api>(\"api\");\n",
+	})
+
+	root := NewSegment()
+	for _, analyzeFunc := range []AnalyzerFunc{analyzeProject, analyzeServices} {
+		if err := analyzeFunc(context.Background(), templateCtx, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, _ := GetInsight[int](root, "aspireServiceCount"); len(count) != 1 || count[0] != 1 {
+		t.Fatalf("expected an app host service, got %v", count)
+	}
+
+	stats := templateCtx.Stats()
+	if stats.FileWalks != 1 {
+		t.Errorf("expected the app host and service directories to be listed from the file index, got %+v", stats)
+	}
+
+	for _, file := range []string{"AppHost.csproj", "Program.cs"} {
+		if _, err := templateCtx.ReadFile(filepath.Join(templatePath, "src", "AppHost", file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if templateCtx.Stats().FileReads != stats.FileReads {
+		t.Errorf("expected the app host files to be cached, got %d reads after %d", templateCtx.Stats().FileReads, stats.FileReads)
+	}
+}
+
+// BenchmarkAnalyzeCatalog analyzes a synthetic catalog with the template context shared by all analyzers
+// and with a template context per analyzer, which loads the project and scans the files for every analyzer.
+func BenchmarkAnalyzeCatalog(b *testing.B) {
+	const templateCount = 50
+
+	rules, err := DefaultRules().Merge(mustParseRules(b, benchmarkRules))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	analysisCtx := AnalysisContext{WorkingDirectory: b.TempDir(), Rules: rules}
+	catalog := []*templates.Template{}
+
+	for i := range templateCount {
+		template := &templates.Template{Source: fmt.Sprintf("https://github.com/contoso/synthetic-%d", i)}
+		templatePath, err := analysisCtx.TemplatePath(template)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		writeSyntheticTemplate(b, templatePath, 200)
+		catalog = append(catalog, template)
+	}
+
+	analyzers, err := analysisCtx.analyzers()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarks := []struct {
+		name   string
+		shared bool
+	}{
+		{name: "shared", shared: true},
+		{name: "perAnalyzer", shared: false},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			total := IOStats{}
+
+			for b.Loop() {
+				for _, template := range catalog {
+					for _, stats := range analyzeBenchmarkTemplate(b, analysisCtx, analyzers, template, benchmark.shared) {
+						total.ProjectLoads += stats.ProjectLoads
+						total.FileWalks += stats.FileWalks
+						total.FileReads += stats.FileReads
+					}
+				}
+			}
+
+			analyzed := float64(b.N * templateCount)
+			b.ReportMetric(float64(total.ProjectLoads)/analyzed, "projectLoads/template")
+			b.ReportMetric(float64(total.FileWalks)/analyzed, "fileWalks/template")
+			b.ReportMetric(float64(total.FileReads)/analyzed, "fileReads/template")
+		})
+	}
+}
+
+// analyzeBenchmarkTemplate runs the analyzers for the template and returns the I/O stats of the template contexts.
+func analyzeBenchmarkTemplate(b *testing.B, analysisCtx AnalysisContext, analyzers []Analyzer, template *templates.Template, shared bool) []IOStats {
+	ctx := context.Background()
+
+	if shared {
+		templateCtx := NewTemplateContext(analysisCtx, template)
+		if _, err := analyzeTemplateContext(ctx, templateCtx); err != nil {
+			b.Fatal(err)
+		}
+
+		return []IOStats{templateCtx.Stats()}
+	}
+
+	stats := []IOStats{}
+	root := NewSegment()
+
+	for _, analyzer := range analyzers {
+		templateCtx := NewTemplateContext(analysisCtx, template)
+		if err := analyzer.Analyze(ctx, templateCtx, root); err != nil {
+			b.Fatal(err)
+		}
+
+		stats = append(stats, templateCtx.Stats())
+	}
+
+	return stats
+}
+
+func mustParseRules(tb testing.TB, rules string) *RuleSet {
+	ruleSet, err := ParseRules([]byte(rules))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return ruleSet
+}
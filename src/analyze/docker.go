@@ -0,0 +1,157 @@
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/wbreza/azd-template-analysis/dockerfile"
+	"github.com/wbreza/azd-template-analysis/project"
+)
+
+// containerHosts are the service hosts that deploy a container image built from a Dockerfile.
+var containerHosts = []string{"containerapp", "aks"}
+
+// rootUsers are the USER values that run the container as root.
+var rootUsers = []string{"root", "0", "root:root", "0:0"}
+
+// analyzeDocker analyzes the Dockerfiles of the container hosted services.
+func analyzeDocker(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	// Load errors are reported once by the project analyzer
+	azdProject, err := templateCtx.Project()
+	if err != nil {
+		return nil
+	}
+
+	dockerSegment := NewSegment()
+	containerServiceCount := 0
+	dockerfileCount := 0
+
+	for serviceName, service := range azdProject.Services {
+		// Services deploying a prebuilt image have no Dockerfile
+		if !slices.Contains(containerHosts, service.Host) || service.Image != "" {
+			continue
+		}
+
+		containerServiceCount++
+
+		serviceSegment := NewSegment()
+		dockerSegment.Segments[serviceName] = serviceSegment
+
+		if analyzeServiceDockerfile(templateCtx, azdProject, serviceName, service, serviceSegment) {
+			dockerfileCount++
+		}
+	}
+
+	if containerServiceCount == 0 {
+		return nil
+	}
+
+	dockerSegment.Insights["containerServiceCount"] = NewInsight(NumberInsight, containerServiceCount)
+	dockerSegment.Insights["dockerfileCount"] = NewInsight(NumberInsight, dockerfileCount)
+	root.Segments["docker"] = dockerSegment
+
+	return nil
+}
+
+// analyzeServiceDockerfile records the insights of the service Dockerfile and returns false when it is missing.
+// A Dockerfile that exists but fails to parse is recorded with the dockerfileParseError insight.
+// The Dockerfile path and build context are relative to the service project path and default to ./Dockerfile and ".".
+func analyzeServiceDockerfile(templateCtx *TemplateContext, azdProject *project.Project, serviceName string, service project.Service, serviceSegment *Segment) bool {
+	servicePath := filepath.Join(azdProject.Root, service.RelativePath)
+	dockerfilePath := "./Dockerfile"
+	dockerContext := "."
+	target := ""
+
+	if service.Docker != nil {
+		if service.Docker.Path != "" {
+			dockerfilePath = service.Docker.Path
+		}
+		if service.Docker.Context != "" {
+			dockerContext = service.Docker.Context
+		}
+
+		target = service.Docker.Target
+	}
+
+	serviceSegment.Data["dockerfile"] = filepath.ToSlash(filepath.Join(service.RelativePath, dockerfilePath))
+	serviceSegment.Data["context"] = filepath.ToSlash(filepath.Join(service.RelativePath, dockerContext))
+
+	parsed, exists, err := loadDockerfile(templateCtx, filepath.Join(servicePath, dockerfilePath))
+	serviceSegment.Insights["hasDockerfile"] = NewInsight(BoolInsight, exists)
+	serviceSegment.Insights["dockerfileParseError"] = NewInsight(BoolInsight, exists && err != nil)
+
+	if err != nil {
+		position, _ := azdProject.Position("services", serviceName)
+		serviceSegment.Errors = append(serviceSegment.Errors, fmt.Sprintf("%s: service '%s': %v", position, serviceName, err))
+		return exists
+	}
+
+	// The image is produced by the build target or the final stage
+	stage := parsed.FinalStage()
+	if target != "" {
+		if targetStage := parsed.Stage(target); targetStage != nil {
+			stage = targetStage
+		}
+	}
+
+	baseImages := []string{}
+	usesLatestTag := false
+	for _, image := range parsed.BaseImages() {
+		baseImages = append(baseImages, image.Reference)
+		usesLatestTag = usesLatestTag || image.UsesLatest()
+	}
+
+	user := stage.User()
+	exposedPorts := stage.ExposedPorts()
+
+	serviceSegment.Data["baseImages"] = baseImages
+	serviceSegment.Data["user"] = user
+	serviceSegment.Data["exposedPorts"] = exposedPorts
+
+	serviceSegment.Insights["isMultiStage"] = NewInsight(BoolInsight, parsed.IsMultiStage())
+	serviceSegment.Insights["usesNonRootUser"] = NewInsight(BoolInsight, user != "" && !slices.Contains(rootUsers, user))
+	serviceSegment.Insights["usesLatestTag"] = NewInsight(BoolInsight, usesLatestTag)
+	serviceSegment.Insights["exposesPorts"] = NewInsight(BoolInsight, len(exposedPorts) > 0)
+	serviceSegment.Insights["stageCount"] = NewInsight(NumberInsight, len(parsed.Stages))
+
+	return true
+}
+
+// loadDockerfile parses the Dockerfile at the path with the contents cached by the template context.
+// It returns whether the Dockerfile exists along with the read or parse error.
+func loadDockerfile(templateCtx *TemplateContext, path string) (*dockerfile.Dockerfile, bool, error) {
+	contents, err := templateCtx.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open Dockerfile %s: %w", path, err)
+	}
+
+	parsed, err := dockerfile.Parse(bytes.NewReader(contents))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse Dockerfile %s: %w", path, err)
+	}
+
+	return parsed, true, nil
+}
+
+// BaseImages returns the base images of the Dockerfiles found for the template analysis.
+func BaseImages(analysis *Segment) []string {
+	if analysis == nil {
+		return nil
+	}
+
+	dockerSegment, has := analysis.Segments["docker"]
+	if !has {
+		return nil
+	}
+
+	baseImages := []string{}
+	for _, serviceSegment := range dockerSegment.Segments {
+		images, _ := serviceSegment.Data["baseImages"].([]string)
+		baseImages = append(baseImages, images...)
+	}
+
+	return baseImages
+}
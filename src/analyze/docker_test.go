@@ -0,0 +1,74 @@
+package analyze
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+func TestAnalyzeDocker(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/containers"}
+	templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir()}, template)
+
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, templatePath, map[string]string{
+		"azure.yaml": `name: containers
+services:
+  api:
+    project: ./src/api
+    host: containerapp
+  web:
+    project: ./src/web
+    host: containerapp
+  worker:
+    project: ./src/worker
+    host: aks
+`,
+		"src/api/Dockerfile": "ARG VERSION\nFROM node:${VERSION}\nUSER node\n",
+		"src/web/Dockerfile": "RUN echo missing base image\n",
+	})
+
+	root := NewSegment()
+	if err := analyzeDocker(context.Background(), templateCtx, root); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerSegment := root.Segments["docker"]
+	if dockerfileCount := dockerSegment.Insights["dockerfileCount"]; dockerfileCount.Value != 2 {
+		t.Errorf("expected 2 Dockerfiles, got %v", dockerfileCount.Value)
+	}
+
+	expected := map[string]map[string]any{
+		"api":    {"hasDockerfile": true, "dockerfileParseError": false, "usesLatestTag": false},
+		"web":    {"hasDockerfile": true, "dockerfileParseError": true},
+		"worker": {"hasDockerfile": false, "dockerfileParseError": false},
+	}
+
+	for serviceName, insights := range expected {
+		for key, value := range insights {
+			if insight := dockerSegment.Segments[serviceName].Insights[key]; insight == nil || insight.Value != value {
+				t.Errorf("expected %s.%s to be %v, got %+v", serviceName, key, value, insight)
+			}
+		}
+	}
+}
+
+func TestAnalyzeDockerWithoutProject(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/containers"}
+	templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir()}, template)
+
+	// The missing azure.yaml is reported by the project analyzer
+	root := NewSegment()
+	if err := analyzeDocker(context.Background(), templateCtx, root); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if HasSegment(root, "docker") {
+		t.Error("expected no docker segment without azure.yaml")
+	}
+}
@@ -0,0 +1,408 @@
+package analyze
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules/default.yaml
+var defaultRulesYaml []byte
+
+// hooksSegment is the segment key of hook script rules, which are recorded in every hook segment.
+const hooksSegment = "hooks"
+
+// rulesAnalyzer is the name of the analyzer evaluating the rules other than hook script rules.
+const rulesAnalyzer = "rules"
+
+// defaultRuleSegment is the segment of rules that do not specify a segment.
+const defaultRuleSegment = "rules"
+
+// analyzerSegments are the segments of the built-in analyzers other than the rules analyzer. Rules only record
+// insights in these segments when the analyzer produced the segment, so a template without an azure.yaml
+// does not gain a project segment from a rule.
+var analyzerSegments = map[string]bool{}
+
+// laterAnalyzerSegments maps the segments of the built-in analyzers registered after the rules analyzer to the
+// analyzer name. These segments do not exist yet when the rules are evaluated, so rules cannot target them.
+var laterAnalyzerSegments = map[string]string{}
+
+func init() {
+	rulesRegistered := false
+
+	for _, analyzer := range builtinAnalyzers {
+		info := analyzer.Info()
+		if info.Name == rulesAnalyzer {
+			rulesRegistered = true
+			continue
+		}
+
+		for _, segment := range info.Segments {
+			analyzerSegments[segment] = true
+			if rulesRegistered {
+				laterAnalyzerSegments[segment] = info.Name
+			}
+		}
+	}
+}
+
+// Rule is a declarative heuristic compiled into an insight of the rule type.
+// Exactly one of the conditions HookScript, FileGlob, FileContent and ProjectPath is set.
+type Rule struct {
+	Insight     string      `yaml:"insight"`
+	Description string      `yaml:"description,omitempty"`
+	Type        InsightType `yaml:"type,omitempty"`
+	Segment     string      `yaml:"segment,omitempty"`
+
+	// HookScript is a regular expression matched against the hook scripts.
+	HookScript string `yaml:"hookScript,omitempty"`
+	// FileGlob is a glob of files relative to the template root.
+	FileGlob string `yaml:"fileGlob,omitempty"`
+	// FileContent matches a regular expression against the files matching a glob.
+	FileContent *FileContentCondition `yaml:"fileContent,omitempty"`
+	// ProjectPath is a dot separated path within azure.yaml such as services.*.docker.remoteBuild.
+	ProjectPath string `yaml:"projectPath,omitempty"`
+	// Equals is compared with the value at the project path. Any value matches when empty.
+	Equals string `yaml:"equals,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+type FileContentCondition struct {
+	Glob    string `yaml:"glob"`
+	Pattern string `yaml:"pattern"`
+}
+
+// RuleSet is a list of compiled rules.
+type RuleSet struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// DefaultRules returns the bundled rules equivalent to the built-in heuristics.
+func DefaultRules() *RuleSet {
+	ruleSet, err := ParseRules(defaultRulesYaml)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rules: %v", err))
+	}
+
+	return ruleSet
+}
+
+// LoadRules loads and compiles the rules file at the path.
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file %s: %w", path, err)
+	}
+
+	ruleSet, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules file %s: %w", path, err)
+	}
+
+	return ruleSet, nil
+}
+
+// ParseRules parses and compiles the rules of a rules file.
+func ParseRules(data []byte) (*RuleSet, error) {
+	var ruleSet RuleSet
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	for i, rule := range ruleSet.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d '%s': %w", i, rule.Insight, err)
+		}
+	}
+
+	if err := ruleSet.validateUnique(); err != nil {
+		return nil, err
+	}
+
+	return &ruleSet, nil
+}
+
+// Merge returns a rule set with the rules of both rule sets. Insights must be unique within a segment.
+func (r *RuleSet) Merge(other *RuleSet) (*RuleSet, error) {
+	merged := &RuleSet{
+		Rules: append(append([]*Rule{}, r.Rules...), other.Rules...),
+	}
+
+	if err := merged.validateUnique(); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// HookRules returns the rules matched against hook scripts.
+func (r *RuleSet) HookRules() []*Rule {
+	rules := []*Rule{}
+	for _, rule := range r.Rules {
+		if rule.HookScript != "" {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+func (r *RuleSet) validateUnique() error {
+	insights := map[string]bool{}
+
+	for _, rule := range r.Rules {
+		key := rule.Segment + "/" + rule.Insight
+		if insights[key] {
+			return fmt.Errorf("duplicate rule for insight '%s' in segment '%s'", rule.Insight, rule.Segment)
+		}
+
+		insights[key] = true
+	}
+
+	return nil
+}
+
+func (rule *Rule) compile() error {
+	if rule.Insight == "" {
+		return fmt.Errorf("insight is required")
+	}
+
+	switch rule.Type {
+	case "":
+		rule.Type = BoolInsight
+	case BoolInsight, NumberInsight:
+	default:
+		return fmt.Errorf("unsupported insight type '%s'", rule.Type)
+	}
+
+	conditions := 0
+	pattern := ""
+
+	if rule.HookScript != "" {
+		conditions++
+		pattern = rule.HookScript
+	}
+	if rule.FileGlob != "" {
+		conditions++
+	}
+	if rule.FileContent != nil {
+		conditions++
+		pattern = rule.FileContent.Pattern
+
+		if rule.FileContent.Glob == "" || rule.FileContent.Pattern == "" {
+			return fmt.Errorf("fileContent requires a glob and a pattern")
+		}
+	}
+	if rule.ProjectPath != "" {
+		conditions++
+	}
+
+	if conditions != 1 {
+		return fmt.Errorf("exactly one of hookScript, fileGlob, fileContent and projectPath is required")
+	}
+
+	if rule.Equals != "" && rule.ProjectPath == "" {
+		return fmt.Errorf("equals requires a projectPath")
+	}
+
+	if rule.HookScript != "" {
+		if rule.Segment != "" && rule.Segment != hooksSegment {
+			return fmt.Errorf("hookScript rules are recorded in the hook segments")
+		}
+
+		rule.Segment = hooksSegment
+	} else if rule.Segment == "" {
+		rule.Segment = defaultRuleSegment
+	}
+
+	if analyzerName, has := laterAnalyzerSegments[rule.Segment]; has {
+		return fmt.Errorf("segment '%s' is recorded by the %s analyzer, which runs after the rules analyzer", rule.Segment, analyzerName)
+	}
+
+	if pattern != "" {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+
+		rule.regex = regex
+	}
+
+	return nil
+}
+
+// insight returns the insight of the rule type for the number of matches.
+func (rule *Rule) insight(matches int) *Insight {
+	if rule.Type == NumberInsight {
+		return NewInsight(NumberInsight, matches)
+	}
+
+	return NewInsight(BoolInsight, matches > 0)
+}
+
+// analyzeHookRules records the insights of the hook script rules for the hook scripts.
+func analyzeHookRules(rules []*Rule, scripts map[string]string, hookSegment *Segment) {
+	for _, rule := range rules {
+		matches := 0
+		for _, script := range scripts {
+			matches += len(rule.regex.FindAllStringIndex(script, -1))
+		}
+
+		hookSegment.Insights[rule.Insight] = rule.insight(matches)
+	}
+}
+
+// analyzeRules evaluates the file and project rules of the analysis context and records their insights.
+func analyzeRules(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	for _, rule := range templateCtx.rules().Rules {
+		if rule.HookScript != "" {
+			continue
+		}
+
+		matches := 0
+
+		switch {
+		case rule.ProjectPath != "":
+			// Project rules do not apply to templates without a valid azure.yaml
+			azdProject, err := templateCtx.Project()
+			if err != nil || azdProject.Node == nil || len(azdProject.Node.Content) == 0 {
+				continue
+			}
+
+			for _, node := range findProjectNodes(azdProject.Node.Content[0], strings.Split(rule.ProjectPath, ".")) {
+				if rule.Equals == "" || (node.Kind == yaml.ScalarNode && node.Value == rule.Equals) {
+					matches++
+				}
+			}
+		default:
+			files, err := templateCtx.Files(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list template files: %w", err)
+			}
+
+			templatePath, _ := templateCtx.Path()
+			matches, err = rule.matchFiles(templateCtx, templatePath, files)
+			if err != nil {
+				return err
+			}
+		}
+
+		segment, has := root.Segments[rule.Segment]
+		if !has {
+			if analyzerSegments[rule.Segment] {
+				continue
+			}
+
+			segment = NewSegment()
+			root.Segments[rule.Segment] = segment
+		}
+
+		segment.Insights[rule.Insight] = rule.insight(matches)
+	}
+
+	return nil
+}
+
+// matchFiles returns the number of files matching the file glob or file content condition of the rule.
+func (rule *Rule) matchFiles(templateCtx *TemplateContext, templatePath string, files []string) (int, error) {
+	glob := rule.FileGlob
+	if rule.FileContent != nil {
+		glob = rule.FileContent.Glob
+	}
+
+	matches := 0
+	for _, file := range files {
+		if !matchGlob(glob, file) {
+			continue
+		}
+
+		if rule.FileContent == nil {
+			matches++
+			continue
+		}
+
+		contents, err := templateCtx.ReadFile(filepath.Join(templatePath, file))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+
+		if rule.regex.Match(contents) {
+			matches++
+		}
+	}
+
+	return matches, nil
+}
+
+// matchGlob matches a slash separated path against a glob where ** matches any number of directories.
+func matchGlob(glob string, path string) bool {
+	return matchGlobParts(strings.Split(glob, "/"), strings.Split(path, "/"))
+}
+
+func matchGlobParts(globParts []string, pathParts []string) bool {
+	if len(globParts) == 0 {
+		return len(pathParts) == 0
+	}
+
+	if globParts[0] == "**" {
+		for i := 0; i <= len(pathParts); i++ {
+			if matchGlobParts(globParts[1:], pathParts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(pathParts) == 0 {
+		return false
+	}
+
+	if matched, err := filepath.Match(globParts[0], pathParts[0]); err != nil || !matched {
+		return false
+	}
+
+	return matchGlobParts(globParts[1:], pathParts[1:])
+}
+
+// findProjectNodes returns the azure.yaml nodes at the path where * matches any key or list item.
+func findProjectNodes(node *yaml.Node, path []string) []*yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if len(path) == 0 {
+		return []*yaml.Node{node}
+	}
+
+	nodes := []*yaml.Node{}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if path[0] == "*" || node.Content[i].Value == path[0] {
+				nodes = append(nodes, findProjectNodes(node.Content[i+1], path[1:])...)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if path[0] == "*" {
+				nodes = append(nodes, findProjectNodes(item, path[1:])...)
+			}
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,216 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+const testRules = `
+rules:
+  - insight: hasBicepParams
+    type: number
+    segment: template
+    fileGlob: 'infra/**/*.bicepparam'
+  - insight: usesManagedIdentity
+    fileContent:
+      glob: '**/*.bicep'
+      pattern: 'Microsoft\.ManagedIdentity'
+  - insight: usesRemoteBuild
+    segment: project
+    projectPath: services.*.docker.remoteBuild
+    equals: true
+  - insight: dockerServiceCount
+    type: number
+    segment: project
+    projectPath: services.*.docker
+`
+
+func writeTestFiles(t testing.TB, root string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAnalyzeRules(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workingDir := t.TempDir()
+	template := &templates.Template{Source: "https://github.com/contoso/todo"}
+	analysisCtx := AnalysisContext{WorkingDirectory: workingDir, Rules: rules}
+
+	templatePath, err := analysisCtx.TemplatePath(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, templatePath, map[string]string{
+		"azure.yaml": `name: todo
+services:
+  api:
+    project: ./api
+    host: containerapp
+    docker:
+      remoteBuild: true
+  web:
+    project: ./web
+    host: containerapp
+    docker:
+      path: ./Dockerfile
+`,
+		"infra/main.bicep":                    "resource identity 'Microsoft.ManagedIdentity/userAssignedIdentities@2023-01-31' = {}",
+		"infra/main.bicepparam":               "using './main.bicep'",
+		"infra/env/dev.bicepparam":            "using '../main.bicep'",
+		"node_modules/pkg/ignored.bicepparam": "",
+	})
+
+	root := NewSegment()
+	root.Segments["template"] = NewSegment()
+	root.Segments["project"] = NewSegment()
+
+	if err := analyzeRules(context.Background(), NewTemplateContext(analysisCtx, template), root); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]map[string]any{
+		"template": {"hasBicepParams": 2},
+		"rules":    {"usesManagedIdentity": true},
+		"project":  {"usesRemoteBuild": true, "dockerServiceCount": 2},
+	}
+
+	for segmentKey, insights := range expected {
+		segment, has := root.Segments[segmentKey]
+		if !has {
+			t.Fatalf("expected segment '%s'", segmentKey)
+		}
+
+		for key, value := range insights {
+			if insight := segment.Insights[key]; insight == nil || insight.Value != value {
+				t.Errorf("expected %s.%s to be %v, got %+v", segmentKey, key, value, insight)
+			}
+		}
+	}
+}
+
+func TestAnalyzeRulesWithoutAzureYaml(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - insight: hasReadme
+    segment: project
+    fileGlob: README.md
+  - insight: hasHookScripts
+    segment: hooks
+    fileGlob: 'scripts/*.sh'
+  - insight: hasBicep
+    segment: template
+    fileGlob: 'infra/*.bicep'
+  - insight: hasGitignore
+    segment: repository
+    fileGlob: .gitignore
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workingDir := t.TempDir()
+	template := &templates.Template{Source: "https://github.com/contoso/no-azure-yaml"}
+	analysisCtx := AnalysisContext{WorkingDirectory: workingDir, Rules: rules}
+
+	templatePath, err := analysisCtx.TemplatePath(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, templatePath, map[string]string{
+		"README.md":        "# sample",
+		"scripts/setup.sh": "echo setup",
+		"infra/main.bicep": "",
+		".gitignore":       ".azure",
+	})
+
+	root := NewSegment()
+	root.Segments["template"] = NewSegment()
+
+	if err := analyzeRules(context.Background(), NewTemplateContext(analysisCtx, template), root); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, segmentKey := range []string{"project", "hooks"} {
+		if _, has := root.Segments[segmentKey]; has {
+			t.Errorf("expected no '%s' segment for a template without azure.yaml", segmentKey)
+		}
+	}
+
+	if insight := root.Segments["template"].Insights["hasBicep"]; insight == nil || insight.Value != true {
+		t.Errorf("expected template.hasBicep to be true, got %+v", insight)
+	}
+
+	repository, has := root.Segments["repository"]
+	if !has {
+		t.Fatal("expected the rule segment 'repository' to be created")
+	}
+
+	if insight := repository.Insights["hasGitignore"]; insight == nil || insight.Value != true {
+		t.Errorf("expected repository.hasGitignore to be true, got %+v", insight)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := map[string]string{
+		"no condition":        "rules:\n  - insight: empty\n",
+		"several conditions":  "rules:\n  - insight: both\n    fileGlob: '*'\n    hookScript: 'az'\n",
+		"invalid pattern":     "rules:\n  - insight: invalid\n    hookScript: '('\n",
+		"unsupported type":    "rules:\n  - insight: text\n    type: string\n    fileGlob: '*'\n",
+		"hook segment":        "rules:\n  - insight: hook\n    segment: project\n    hookScript: 'az'\n",
+		"unknown field":       "rules:\n  - insight: typo\n    fileGlobs: '*'\n",
+		"duplicate insight":   "rules:\n  - insight: dup\n    fileGlob: '*'\n  - insight: dup\n    fileGlob: '*.md'\n",
+		"equals without path": "rules:\n  - insight: equals\n    fileGlob: '*'\n    equals: x\n",
+		"later segment":       "rules:\n  - insight: late\n    segment: catalog\n    fileGlob: '*'\n",
+	}
+
+	for name, rules := range tests {
+		if _, err := ParseRules([]byte(rules)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := DefaultRules().Merge(DefaultRules()); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate error merging the default rules, got %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob     string
+		path     string
+		expected bool
+	}{
+		{"infra/*.bicep", "infra/main.bicep", true},
+		{"infra/*.bicep", "infra/core/host.bicep", false},
+		{"infra/**/*.bicep", "infra/main.bicep", true},
+		{"infra/**/*.bicep", "infra/core/host/aca.bicep", true},
+		{"**/Dockerfile", "src/api/Dockerfile", true},
+		{"**/Dockerfile", "Dockerfile", true},
+		{".github/workflows/*.yml", ".github/workflows/azure-dev.yaml", false},
+	}
+
+	for _, test := range tests {
+		if actual := matchGlob(test.glob, test.path); actual != test.expected {
+			t.Errorf("matchGlob(%s, %s) = %v, expected %v", test.glob, test.path, actual, test.expected)
+		}
+	}
+}
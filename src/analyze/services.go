@@ -0,0 +1,151 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// languageManifests maps the manifest files of a project directory to the language family they indicate.
+// Patterns are matched against the file names within the service directory.
+var languageManifests = []struct {
+	pattern  string
+	language string
+}{
+	{"package.json", "javascript"},
+	{"pyproject.toml", "python"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"*.csproj", "dotnet"},
+	{"*.fsproj", "dotnet"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "java"},
+	{"go.mod", "go"},
+}
+
+// languageFamilies maps the azure.yaml language values to the language family detected from manifests.
+// The lang-* project insights and the catalog language checks use the same families.
+var languageFamilies = map[string]string{
+	"dotnet":     "dotnet",
+	"csharp":     "dotnet",
+	"fsharp":     "dotnet",
+	"py":         "python",
+	"python":     "python",
+	"js":         "javascript",
+	"node":       "javascript",
+	"ts":         "javascript",
+	"javascript": "javascript",
+	"typescript": "javascript",
+	"java":       "java",
+	"go":         "go",
+}
+
+// analyzeServices verifies the project path of every service exists and that the declared language
+// matches the language detected from the manifests within the service directory.
+func analyzeServices(ctx context.Context, templateCtx *TemplateContext, root *Segment) error {
+	// Load errors are reported once by the project analyzer
+	azdProject, err := templateCtx.Project()
+	if err != nil {
+		return nil
+	}
+
+	if len(azdProject.Services) == 0 {
+		return nil
+	}
+
+	servicesSegment := NewSegment()
+	root.Segments["serviceChecks"] = servicesSegment
+
+	missingPathCount := 0
+	languageMismatchCount := 0
+
+	for serviceName, service := range azdProject.Services {
+		serviceSegment := NewSegment()
+		servicesSegment.Segments[serviceName] = serviceSegment
+
+		position, has := azdProject.Position("services", serviceName, "project")
+		if !has {
+			position, _ = azdProject.Position("services", serviceName)
+		}
+
+		serviceSegment.Data["project"] = service.RelativePath
+		serviceSegment.Data["language"] = service.Language
+
+		servicePath := filepath.Join(azdProject.Root, service.RelativePath)
+		info, err := os.Stat(servicePath)
+		pathExists := err == nil
+		serviceSegment.Insights["pathExists"] = NewInsight(BoolInsight, pathExists)
+
+		if !pathExists {
+			missingPathCount++
+			serviceSegment.Errors = append(serviceSegment.Errors,
+				fmt.Sprintf("%s: service '%s' project path '%s' does not exist", position, serviceName, service.RelativePath))
+
+			continue
+		}
+
+		detected := detectLanguages(ctx, templateCtx, servicePath, info)
+		serviceSegment.Data["detectedLanguages"] = detected
+
+		declared, known := languageFamilies[strings.ToLower(service.Language)]
+		languageMatches := !known || len(detected) == 0 || slices.Contains(detected, declared)
+		serviceSegment.Insights["languageMatches"] = NewInsight(BoolInsight, languageMatches)
+
+		if !languageMatches {
+			languageMismatchCount++
+
+			languagePosition, has := azdProject.Position("services", serviceName, "language")
+			if !has {
+				languagePosition = position
+			}
+
+			serviceSegment.Errors = append(serviceSegment.Errors,
+				fmt.Sprintf("%s: service '%s' declares language '%s' but %s was detected",
+					languagePosition, serviceName, service.Language, strings.Join(detected, ", ")))
+		}
+	}
+
+	servicesSegment.Insights["hasMissingServicePath"] = NewInsight(BoolInsight, missingPathCount > 0)
+	servicesSegment.Insights["hasLanguageMismatch"] = NewInsight(BoolInsight, languageMismatchCount > 0)
+	servicesSegment.Insights["missingServicePathCount"] = NewInsight(NumberInsight, missingPathCount)
+	servicesSegment.Insights["languageMismatchCount"] = NewInsight(NumberInsight, languageMismatchCount)
+
+	return nil
+}
+
+// detectLanguages returns the sorted language families of the manifests within the service directory.
+// Services may reference a project file directly, in which case the language is detected from that file.
+func detectLanguages(ctx context.Context, templateCtx *TemplateContext, servicePath string, info os.FileInfo) []string {
+	fileNames := []string{}
+
+	if info.IsDir() {
+		files, err := templateCtx.DirFiles(ctx, servicePath)
+		if err != nil {
+			return []string{}
+		}
+
+		for _, file := range files {
+			fileNames = append(fileNames, filepath.Base(file))
+		}
+	} else {
+		fileNames = append(fileNames, info.Name())
+	}
+
+	languages := []string{}
+	for _, manifest := range languageManifests {
+		for _, fileName := range fileNames {
+			if matched, _ := filepath.Match(manifest.pattern, fileName); matched && !slices.Contains(languages, manifest.language) {
+				languages = append(languages, manifest.language)
+			}
+		}
+	}
+
+	sort.Strings(languages)
+
+	return languages
+}
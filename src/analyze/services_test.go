@@ -0,0 +1,120 @@
+package analyze
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+func TestAnalyzeServices(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/services"}
+	templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir()}, template)
+
+	templatePath, err := templateCtx.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, templatePath, map[string]string{
+		"azure.yaml": `name: services
+services:
+  missing:
+    project: ./src/missing
+    language: python
+    host: containerapp
+  mismatch:
+    project: ./src/web
+    language: python
+    host: appservice
+  csproj:
+    project: ./src/api/Api.csproj
+    language: csharp
+    host: appservice
+  node:
+    project: ./src/web
+    language: node
+    host: appservice
+  unknown:
+    project: ./src/web
+    language: cobol
+    host: appservice
+`,
+		"src/web/package.json": "{}\n",
+		"src/api/Api.csproj":   "<Project Sdk=\"Microsoft.NET.Sdk.Web\" />\n",
+		"src/api/package.json": "{}\n",
+	})
+
+	root := NewSegment()
+	if err := analyzeServices(context.Background(), templateCtx, root); err != nil {
+		t.Fatal(err)
+	}
+
+	servicesSegment := root.Segments["serviceChecks"]
+
+	tests := []struct {
+		service         string
+		pathExists      bool
+		languageMatches bool
+		detected        []string
+	}{
+		{service: "missing", pathExists: false},
+		{service: "mismatch", pathExists: true, languageMatches: false, detected: []string{"javascript"}},
+		{service: "csproj", pathExists: true, languageMatches: true, detected: []string{"dotnet"}},
+		{service: "node", pathExists: true, languageMatches: true, detected: []string{"javascript"}},
+		{service: "unknown", pathExists: true, languageMatches: true, detected: []string{"javascript"}},
+	}
+
+	for _, test := range tests {
+		serviceSegment := servicesSegment.Segments[test.service]
+
+		if pathExists := serviceSegment.Insights["pathExists"].Value; pathExists != test.pathExists {
+			t.Errorf("%s: expected pathExists %v, got %v", test.service, test.pathExists, pathExists)
+		}
+
+		if !test.pathExists {
+			if len(serviceSegment.Errors) != 1 {
+				t.Errorf("%s: expected a missing path error, got %v", test.service, serviceSegment.Errors)
+			}
+
+			continue
+		}
+
+		if languageMatches := serviceSegment.Insights["languageMatches"].Value; languageMatches != test.languageMatches {
+			t.Errorf("%s: expected languageMatches %v, got %v", test.service, test.languageMatches, languageMatches)
+		}
+
+		if detected, _ := serviceSegment.Data["detectedLanguages"].([]string); !slices.Equal(detected, test.detected) {
+			t.Errorf("%s: expected detected languages %v, got %v", test.service, test.detected, detected)
+		}
+	}
+
+	expected := map[string]any{
+		"hasMissingServicePath":   true,
+		"hasLanguageMismatch":     true,
+		"missingServicePathCount": 1,
+		"languageMismatchCount":   1,
+	}
+
+	for key, value := range expected {
+		if insight := servicesSegment.Insights[key]; insight == nil || insight.Value != value {
+			t.Errorf("expected %s to be %v, got %+v", key, value, insight)
+		}
+	}
+}
+
+func TestAnalyzeServicesWithoutProject(t *testing.T) {
+	template := &templates.Template{Source: "https://github.com/contoso/services"}
+	templateCtx := NewTemplateContext(AnalysisContext{WorkingDirectory: t.TempDir()}, template)
+
+	// The missing azure.yaml is reported by the project analyzer
+	root := NewSegment()
+	if err := analyzeServices(context.Background(), templateCtx, root); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if HasSegment(root, "serviceChecks") {
+		t.Error("expected no service checks without azure.yaml")
+	}
+}
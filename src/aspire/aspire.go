@@ -0,0 +1,239 @@
+package aspire
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// appHostMarkers are the contents of a project file that identify a .NET Aspire app host.
+var appHostMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)Include="Aspire\.Hosting[\w.]*"`),
+	regexp.MustCompile(`(?i)"Aspire\.AppHost\.Sdk[/"]`),
+	regexp.MustCompile(`(?i)<IsAspireHost>\s*true\s*</IsAspireHost>`),
+}
+
+// resourceRegex matches the resources added to the distributed application builder, i.e.
+// builder.AddProject<Projects.Api>("api") or builder.AddRedis("cache").
+var resourceRegex = regexp.MustCompile(`\.Add(\w+)(?:<([\w.]+)>)?\(\s*"([^"]+)"`)
+
+// serviceLanguages maps the resource kinds deployed as services by azd to the language of the service.
+// Container based resources have no language.
+var serviceLanguages = map[string]string{
+	"Project":       "dotnet",
+	"CSharpApp":     "dotnet",
+	"NpmApp":        "javascript",
+	"NodeApp":       "javascript",
+	"JavaScriptApp": "javascript",
+	"ViteApp":       "javascript",
+	"PythonApp":     "python",
+	"PythonProject": "python",
+	"PythonScript":  "python",
+	"UvicornApp":    "python",
+	"Container":     "",
+	"Dockerfile":    "",
+}
+
+// Resource is a resource declared in the app host program.
+type Resource struct {
+	// Kind is the name of the Add method without the Add prefix, i.e. Project, Redis or Container.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Type is the generic type argument of the Add method, i.e. Projects.Api.
+	Type string `json:"type,omitempty"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// IsService returns true when azd deploys the resource as a service instead of provisioning it.
+func (r *Resource) IsService() bool {
+	_, has := serviceLanguages[r.Kind]
+	return has
+}
+
+// Language returns the language of a service resource, empty for container based or backing resources.
+func (r *Resource) Language() string {
+	return serviceLanguages[r.Kind]
+}
+
+// AppHost is a .NET Aspire app host project.
+type AppHost struct {
+	// Path is the path of the app host project file.
+	Path      string      `json:"path"`
+	Resources []*Resource `json:"resources"`
+}
+
+// Services returns the resources azd deploys as services.
+func (a *AppHost) Services() []*Resource {
+	services := []*Resource{}
+	for _, resource := range a.Resources {
+		if resource.IsService() {
+			services = append(services, resource)
+		}
+	}
+
+	return services
+}
+
+// Kinds returns the sorted distinct resource kinds declared in the app host.
+func (a *AppHost) Kinds() []string {
+	kinds := []string{}
+	for _, resource := range a.Resources {
+		if !slices.Contains(kinds, resource.Kind) {
+			kinds = append(kinds, resource.Kind)
+		}
+	}
+
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// FileSystem provides the files of an app host by path, which allows callers to serve them from a cache.
+type FileSystem interface {
+	// Files returns the paths of the files directly within the directory.
+	Files(dir string) ([]string, error)
+	// ReadFile returns the contents of the file.
+	ReadFile(path string) ([]byte, error)
+}
+
+// osFileSystem reads the files from disk.
+type osFileSystem struct{}
+
+func (osFileSystem) Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func (osFileSystem) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// FindAppHost returns the app host project file at the path, which is either a project file or a directory
+// containing one. Returns false when the path is not a .NET Aspire app host.
+func FindAppHost(path string) (string, bool) {
+	return FindAppHostFS(osFileSystem{}, path)
+}
+
+// FindAppHostFS returns the app host project file at the path with the files of the file system.
+func FindAppHostFS(fsys FileSystem, path string) (string, bool) {
+	projectFiles := []string{path}
+	if filepath.Ext(path) != ".csproj" {
+		files, err := fsys.Files(path)
+		if err != nil {
+			return "", false
+		}
+
+		projectFiles = files
+	}
+
+	for _, projectFile := range projectFiles {
+		if filepath.Ext(projectFile) != ".csproj" {
+			continue
+		}
+
+		contents, err := fsys.ReadFile(projectFile)
+		if err != nil {
+			continue
+		}
+
+		for _, marker := range appHostMarkers {
+			if marker.Match(contents) {
+				return projectFile, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Load loads the resources declared in the C# files of the app host project.
+func Load(projectFile string) (*AppHost, error) {
+	return LoadFS(osFileSystem{}, projectFile)
+}
+
+// LoadFS loads the resources declared in the C# files of the app host project with the files of the file system.
+func LoadFS(fsys FileSystem, projectFile string) (*AppHost, error) {
+	appHost := &AppHost{
+		Path:      projectFile,
+		Resources: []*Resource{},
+	}
+
+	files, err := fsys.Files(filepath.Dir(projectFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find app host source files: %w", err)
+	}
+
+	sourceFiles := []string{}
+	for _, file := range files {
+		if filepath.Ext(file) == ".cs" {
+			sourceFiles = append(sourceFiles, file)
+		}
+	}
+
+	sort.Strings(sourceFiles)
+
+	for _, sourceFile := range sourceFiles {
+		contents, err := fsys.ReadFile(sourceFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open app host source file %s: %w", sourceFile, err)
+		}
+
+		resources, err := parseResources(sourceFile, contents)
+		if err != nil {
+			return nil, err
+		}
+
+		appHost.Resources = append(appHost.Resources, resources...)
+	}
+
+	return appHost, nil
+}
+
+func parseResources(sourceFile string, contents []byte) ([]*Resource, error) {
+	resources := []*Resource{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+
+		for _, match := range resourceRegex.FindAllStringSubmatch(line, -1) {
+			resources = append(resources, &Resource{
+				Kind: match[1],
+				Type: match[2],
+				Name: match[3],
+				File: filepath.Base(sourceFile),
+				Line: lineNumber,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read app host source file %s: %w", sourceFile, err)
+	}
+
+	return resources, nil
+}
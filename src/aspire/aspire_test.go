@@ -0,0 +1,52 @@
+package aspire
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFindAppHost(t *testing.T) {
+	projectFile, isAppHost := FindAppHost(filepath.Join("testdata", "apphost", "Shop.AppHost"))
+	if !isAppHost || filepath.Base(projectFile) != "Shop.AppHost.csproj" {
+		t.Fatalf("expected app host project, got '%s'", projectFile)
+	}
+
+	if _, isAppHost := FindAppHost(filepath.Join("testdata", "apphost", "Shop.Api", "Shop.Api.csproj")); isAppHost {
+		t.Error("expected web project not to be an app host")
+	}
+
+	if _, isAppHost := FindAppHost(filepath.Join("testdata", "missing")); isAppHost {
+		t.Error("expected missing path not to be an app host")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	appHost, err := Load(filepath.Join("testdata", "apphost", "Shop.AppHost", "Shop.AppHost.csproj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, resource := range appHost.Resources {
+		names = append(names, resource.Name)
+	}
+
+	if !slices.Equal(names, []string{"cache", "postgres", "catalogdb", "api", "web"}) {
+		t.Fatalf("unexpected resources %v", names)
+	}
+
+	api := appHost.Resources[3]
+	if api.Kind != "Project" || api.Type != "Projects.Shop_Api" || api.File != "Program.cs" || api.Line != 7 {
+		t.Errorf("unexpected project resource %+v", api)
+	}
+
+	services := appHost.Services()
+	if len(services) != 2 || services[0].Language() != "dotnet" || services[1].Language() != "javascript" {
+		t.Errorf("unexpected services %+v", services)
+	}
+
+	if !slices.Equal(appHost.Kinds(), []string{"Database", "NpmApp", "Postgres", "Project", "Redis"}) {
+		t.Errorf("unexpected kinds %v", appHost.Kinds())
+	}
+}
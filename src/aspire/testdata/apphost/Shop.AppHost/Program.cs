@@ -0,0 +1,14 @@
+var builder = DistributedApplication.CreateBuilder(args);
+
+var cache = builder.AddRedis("cache");
+var db = builder.AddPostgres("postgres").AddDatabase("catalogdb");
+
+// builder.AddProject<Projects.Legacy>("legacy");
+var api = builder.AddProject<Projects.Shop_Api>("api")
+    .WithReference(cache)
+    .WithReference(db);
+
+builder.AddNpmApp("web", "../Shop.Web")
+    .WithReference(api);
+
+builder.Build().Run();
@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -20,10 +26,20 @@ type analyzeFlags struct {
 	template  string
 	filePath  string
 	outputDir string
+	locked    bool
+
+	analyzers     []string
+	skipAnalyzers []string
+	rules         []string
+	parallel      int
+
+	timeout         time.Duration
+	templateTimeout time.Duration
 }
 
 func newAnalyzeCmd(root *cobra.Command) {
 	flags := &analyzeFlags{}
+	analyzerNames := strings.Join(analyze.DefaultRegistry.Names(), ", ")
 
 	analyze := &cobra.Command{
 		Use: "analyze",
@@ -41,118 +57,443 @@ func newAnalyzeCmd(root *cobra.Command) {
 				flags.outputDir = filepath.Join(cwd, "output")
 			}
 
-			if err := os.MkdirAll(flags.outputDir, 0755); err != nil {
-				return fmt.Errorf("failed to create output directory: %w", err)
-			}
+			return runAnalyze(cmd.Context(), flags)
+		},
+	}
 
-			templateList, err := templates.Load(filepath.Join(flags.filePath, "templates.json"))
-			if err != nil {
-				return fmt.Errorf("failed to load templates: %w", err)
-			}
+	analyze.Flags().StringVarP(&flags.template, "template", "t", "", "Template to analyze.")
+	analyze.Flags().StringVarP(&flags.filePath, "file", "f", "", "Path to the template sync directory.")
+	analyze.Flags().StringVarP(&flags.outputDir, "output", "o", "", "Path to the output directory.")
+	analyze.Flags().BoolVar(&flags.locked, "locked", false, "Analyze the templates at the exact commits recorded in the templates.lock file.")
+	analyze.Flags().StringSliceVar(&flags.analyzers, "analyzers", nil, fmt.Sprintf(
+		"Analyzers to run with their dependencies, all when not specified (%s).", analyzerNames))
+	analyze.Flags().StringSliceVar(&flags.skipAnalyzers, "skip-analyzers", nil, "Analyzers to skip.")
+	analyze.Flags().StringArrayVar(&flags.rules, "rules", nil, "Path to a rules file with heuristic rules added to the default rules.")
+	analyze.Flags().IntVar(&flags.parallel, "parallel", 1, "The number of templates analyzed concurrently.")
+	analyze.Flags().DurationVar(&flags.timeout, "timeout", 0, "The maximum duration of the whole analysis, templates not analyzed in time are reported as failed (0 to disable).")
+	analyze.Flags().DurationVar(&flags.templateTimeout, "template-timeout", 5*time.Minute, "The maximum duration to analyze a single template (0 to disable).")
 
-			allResults := []*analyze.TemplateWithResults{}
+	root.AddCommand(analyze)
+}
 
-			analysisCtx := analyze.AnalysisContext{
-				WorkingDirectory: flags.filePath,
-			}
+// runAnalyze analyzes the templates of the sync directory and writes the analysis to the output directory.
+// The output files do not depend on the number of templates analyzed in parallel.
+func runAnalyze(ctx context.Context, flags *analyzeFlags) error {
+	runCtx, cancel := withTimeout(ctx, flags.timeout)
+	defer cancel()
 
-			for _, template := range templateList {
-				if flags.template == "" || flags.template == template.Source {
-					templateDir := filepath.Join(flags.filePath, filepath.Base(template.Source))
-					var templateAnalysis *analyze.Segment
-
-					templateAnalysis, err = analyze.AnalyzeTemplate(analysisCtx, template)
-					if err != nil {
-						templateAnalysis = &analyze.Segment{
-							Errors: []string{err.Error()},
-						}
-
-						color.Red("Failed to analyze template '%s': %w", templateDir, err)
-					} else {
-						color.Green("Template '%s' analyzed successfully.", templateDir)
-					}
-
-					allResults = append(allResults, &analyze.TemplateWithResults{
-						Template: template,
-						Analysis: templateAnalysis,
-					})
-				}
-			}
+	if err := os.MkdirAll(flags.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
 
-			resultBytes, err := json.MarshalIndent(allResults, "", " ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal results: %w", err)
-			}
+	templateList, err := templates.Load(filepath.Join(flags.filePath, "templates.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
 
-			// Write raw results
-			if err := os.WriteFile(filepath.Join(flags.outputDir, "raw.json"), resultBytes, 0644); err != nil {
-				return fmt.Errorf("failed to write results: %w", err)
-			}
+	var lock *templates.Lock
+	if flags.locked {
+		lock, err = templates.LoadLock(filepath.Join(flags.filePath, templates.LockFileName))
+		if err != nil {
+			return fmt.Errorf("failed to load lock file: %w", err)
+		}
+	}
 
-			// Write template results
-			templatesFilePath := filepath.Join(flags.outputDir, "templates.csv")
-			templateMetrics, err := writeAnalysisToCsv(templatesFilePath, allResults, "template", false)
-			if err != nil {
-				return fmt.Errorf("failed to write root analysis to csv: %w", err)
-			}
+	// Templates that failed to sync are reported as not synced instead of analyzing a missing or stale clone
+	syncReport, err := templates.LoadSyncReport(filepath.Join(flags.filePath, templates.SyncReportFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to load sync report: %w", err)
+	}
 
-			templateSection := analyze.MetricSection{
-				Title:       "Templates",
-				Description: "Based on all templates",
-				Metrics:     templateMetrics,
-			}
+	if flags.parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
 
-			// Write project results
-			projectsFilePath := filepath.Join(flags.outputDir, "projects.csv")
-			projectMetrics, err := writeAnalysisToCsv(projectsFilePath, allResults, "project", false)
-			if err != nil {
-				return fmt.Errorf("failed to write root analysis to csv: %w", err)
-			}
+	syncer := templates.NewSyncer(templates.NewGitCli())
+	allResults := []*analyze.TemplateWithResults{}
 
-			projectSection := analyze.MetricSection{
-				Title:       "Projects",
-				Description: "Based on all templates with azure.yaml",
-				Metrics:     projectMetrics,
-			}
+	analyzers, err := analyze.DefaultRegistry.Resolve(flags.analyzers, flags.skipAnalyzers)
+	if err != nil {
+		return err
+	}
 
-			// Write hook results
-			hooksFilePath := filepath.Join(flags.outputDir, "hooks.csv")
-			hookMetrics, err := writeAnalysisToCsv(hooksFilePath, allResults, "hooks", true)
-			if err != nil {
-				return fmt.Errorf("failed to write hooks analysis to csv: %w", err)
-			}
+	rules := analyze.DefaultRules()
+	for _, rulesPath := range flags.rules {
+		fileRules, err := analyze.LoadRules(rulesPath)
+		if err != nil {
+			return err
+		}
+
+		rules, err = rules.Merge(fileRules)
+		if err != nil {
+			return fmt.Errorf("failed to add rules from %s: %w", rulesPath, err)
+		}
+	}
 
-			hookSection := analyze.MetricSection{
-				Title:       "Hooks",
-				Description: "Based on templates that use hooks",
-				Metrics:     hookMetrics,
+	analysisCtx := analyze.AnalysisContext{
+		WorkingDirectory: flags.filePath,
+		Analyzers:        analyzers,
+		Rules:            rules,
+	}
+
+	selected := []*templates.Template{}
+	for _, template := range templateList {
+		if flags.template == "" || templates.SourceKey(flags.template) == templates.SourceKey(template.Source) {
+			selected = append(selected, template)
+		}
+	}
+
+	// Templates within the same repository share a clone, locked checkouts of a repository must not run concurrently
+	var repoLocks sync.Map
+
+	pending := analyzeAll(flags.parallel, selected, func(template *templates.Template) *templateOutcome {
+		if syncFailure(syncReport, template) != nil {
+			return nil
+		}
+
+		if lock != nil {
+			repoLock, _ := repoLocks.LoadOrStore(templates.RepoKey(template.Source), &sync.Mutex{})
+			repoLock.(*sync.Mutex).Lock()
+			defer repoLock.(*sync.Mutex).Unlock()
+		}
+
+		commit, templateAnalysis, err := analyzeTemplate(runCtx, flags.templateTimeout, syncer, lock, analysisCtx, template)
+
+		return &templateOutcome{
+			commit:   commit,
+			analysis: templateAnalysis,
+			err:      err,
+		}
+	})
+
+	// Outcomes are reported in catalog order so the console and output files match a serial run
+	for i, template := range selected {
+		templateDir, _ := analysisCtx.TemplatePath(template)
+		outcome := <-pending[i]
+
+		if syncResult := syncFailure(syncReport, template); syncResult != nil {
+			color.Yellow("Template '%s' not synced (%s), skipping analysis.", templateDir, syncResult.Failure)
+
+			allResults = append(allResults, &analyze.TemplateWithResults{
+				Template: template,
+				Status:   analyze.AnalysisNotSynced,
+				Analysis: &analyze.Segment{
+					Errors: []string{fmt.Sprintf("template not synced (%s): %s", syncResult.Failure, syncResult.Error)},
+				},
+			})
+
+			continue
+		}
+
+		// Ctrl-C aborts the run, timed out templates are recorded as failures
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("analysis cancelled: %w", err)
+		}
+
+		status := analyze.AnalysisSucceeded
+		templateAnalysis := outcome.analysis
+
+		if outcome.err != nil {
+			status = analyze.AnalysisFailed
+			templateAnalysis = &analyze.Segment{
+				Errors: []string{outcome.err.Error()},
 			}
 
-			fmt.Print(templateSection.String())
-			fmt.Print(projectSection.String())
-			fmt.Print(hookSection.String())
+			color.Red("Failed to analyze template '%s': %v", templateDir, outcome.err)
+		} else {
+			color.Green("Template '%s' analyzed successfully.", templateDir)
+		}
 
-			// Write markdown
-			markdownFile, err := os.Create(filepath.Join(flags.outputDir, "output.md"))
-			if err != nil {
-				return fmt.Errorf("failed to create markdown file: %w", err)
+		allResults = append(allResults, &analyze.TemplateWithResults{
+			Template: template,
+			Commit:   outcome.commit,
+			Status:   status,
+			Analysis: templateAnalysis,
+		})
+	}
+
+	resultBytes, err := json.MarshalIndent(allResults, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	// Write raw results
+	if err := os.WriteFile(filepath.Join(flags.outputDir, "raw.json"), resultBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	// Write template results
+	templatesFilePath := filepath.Join(flags.outputDir, "templates.csv")
+	templateMetrics, err := writeAnalysisToCsv(templatesFilePath, allResults, "template", false)
+	if err != nil {
+		return fmt.Errorf("failed to write root analysis to csv: %w", err)
+	}
+
+	templateSection := analyze.MetricSection{
+		Title:       "Templates",
+		Description: "Based on all templates",
+		Metrics:     templateMetrics,
+	}
+
+	// Write project results
+	projectsFilePath := filepath.Join(flags.outputDir, "projects.csv")
+	projectMetrics, err := writeAnalysisToCsv(projectsFilePath, allResults, "project", false)
+	if err != nil {
+		return fmt.Errorf("failed to write root analysis to csv: %w", err)
+	}
+
+	projectSection := analyze.MetricSection{
+		Title:       "Projects",
+		Description: "Based on all templates with azure.yaml",
+		Metrics:     projectMetrics,
+	}
+
+	// Write hook results
+	hooksFilePath := filepath.Join(flags.outputDir, "hooks.csv")
+	hookMetrics, err := writeAnalysisToCsv(hooksFilePath, allResults, "hooks", true)
+	if err != nil {
+		return fmt.Errorf("failed to write hooks analysis to csv: %w", err)
+	}
+
+	hookSection := analyze.MetricSection{
+		Title:       "Hooks",
+		Description: "Based on templates that use hooks",
+		Metrics:     hookMetrics,
+	}
+
+	// Write service results
+	servicesFilePath := filepath.Join(flags.outputDir, "services.csv")
+	serviceMetrics, err := writeAnalysisToCsv(servicesFilePath, allResults, "serviceChecks", false)
+	if err != nil {
+		return fmt.Errorf("failed to write service analysis to csv: %w", err)
+	}
+
+	serviceSection := analyze.MetricSection{
+		Title:       "Services",
+		Description: "Based on templates with services",
+		Metrics:     serviceMetrics,
+	}
+
+	// Write docker results
+	dockerFilePath := filepath.Join(flags.outputDir, "docker.csv")
+	dockerMetrics, err := writeAnalysisToCsv(dockerFilePath, allResults, "docker", true)
+	if err != nil {
+		return fmt.Errorf("failed to write docker analysis to csv: %w", err)
+	}
+
+	dockerSection := analyze.MetricSection{
+		Title:       "Docker",
+		Description: "Based on templates with containerapp or aks services",
+		Metrics:     dockerMetrics,
+	}
+
+	// Write catalog results
+	catalogFilePath := filepath.Join(flags.outputDir, "catalog.csv")
+	catalogMetrics, err := writeAnalysisToCsv(catalogFilePath, allResults, "catalog", false)
+	if err != nil {
+		return fmt.Errorf("failed to write catalog analysis to csv: %w", err)
+	}
+
+	catalogSection := analyze.MetricSection{
+		Title:       "Catalog",
+		Description: "Catalog metadata compared with the template repository",
+		Metrics:     catalogMetrics,
+	}
+
+	fmt.Print(templateSection.String())
+	fmt.Print(projectSection.String())
+	fmt.Print(hookSection.String())
+	fmt.Print(serviceSection.String())
+	fmt.Print(dockerSection.String())
+	fmt.Print(catalogSection.String())
+
+	// Write markdown
+	markdownFile, err := os.Create(filepath.Join(flags.outputDir, "output.md"))
+	if err != nil {
+		return fmt.Errorf("failed to create markdown file: %w", err)
+	}
+
+	defer markdownFile.Close()
+
+	fmt.Fprint(markdownFile, templateSection.Markdown())
+	fmt.Fprint(markdownFile, projectSection.Markdown())
+	fmt.Fprint(markdownFile, hookSection.Markdown())
+	fmt.Fprint(markdownFile, serviceSection.Markdown())
+	fmt.Fprint(markdownFile, dockerSection.Markdown())
+	fmt.Fprint(markdownFile, catalogSection.Markdown())
+	fmt.Fprint(markdownFile, discrepanciesMarkdown(allResults))
+	fmt.Fprint(markdownFile, baseImagesMarkdown(allResults))
+
+	return nil
+}
+
+// templateOutcome is the result of analyzing a single template.
+type templateOutcome struct {
+	commit   string
+	analysis *analyze.Segment
+	err      error
+}
+
+// analyzeAll analyzes the templates with the specified number of workers. The outcome of every template is
+// delivered on its own channel so outcomes can be reported in catalog order while later templates are analyzed.
+func analyzeAll(
+	parallel int,
+	templateList []*templates.Template,
+	analyzeFunc func(template *templates.Template) *templateOutcome,
+) []chan *templateOutcome {
+	pending := make([]chan *templateOutcome, len(templateList))
+	jobs := make(chan int, len(templateList))
+
+	for i := range templateList {
+		pending[i] = make(chan *templateOutcome, 1)
+		jobs <- i
+	}
+
+	close(jobs)
+
+	for range min(parallel, len(templateList)) {
+		go func() {
+			for i := range jobs {
+				pending[i] <- analyzeFunc(templateList[i])
 			}
+		}()
+	}
 
-			defer markdownFile.Close()
+	return pending
+}
 
-			fmt.Fprint(markdownFile, templateSection.Markdown())
-			fmt.Fprint(markdownFile, projectSection.Markdown())
-			fmt.Fprint(markdownFile, hookSection.Markdown())
+// syncFailure returns the sync result of the template when it failed to sync.
+func syncFailure(syncReport *templates.SyncReport, template *templates.Template) *templates.SyncResult {
+	if syncReport == nil {
+		return nil
+	}
 
-			return nil
-		},
+	syncResult := syncReport.Find(template.Source)
+	if syncResult == nil || syncResult.Status != templates.SyncFailed {
+		return nil
 	}
 
-	analyze.Flags().StringVarP(&flags.template, "template", "t", "", "Template to analyze.")
-	analyze.Flags().StringVarP(&flags.filePath, "file", "f", "", "Path to the template sync directory.")
-	analyze.Flags().StringVarP(&flags.outputDir, "output", "o", "", "Path to the output directory.")
+	return syncResult
+}
 
-	root.AddCommand(analyze)
+// analyzeTemplate analyzes the template within the template timeout and returns the analyzed commit.
+// When a lock is specified the locked commit is checked out before the analysis.
+func analyzeTemplate(
+	ctx context.Context,
+	timeout time.Duration,
+	syncer *templates.Syncer,
+	lock *templates.Lock,
+	analysisCtx analyze.AnalysisContext,
+	template *templates.Template,
+) (string, *analyze.Segment, error) {
+	templateCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	var commit string
+
+	if lock != nil {
+		templateDir, err := analysisCtx.TemplatePath(template)
+		if err != nil {
+			return "", nil, err
+		}
+
+		commit, err = checkoutLocked(templateCtx, syncer, lock, template, templateDir)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	templateAnalysis, err := analyze.AnalyzeTemplate(templateCtx, analysisCtx, template)
+	if err != nil {
+		return commit, nil, err
+	}
+
+	return commit, templateAnalysis, nil
+}
+
+// checkoutLocked checks out the locked commit of the template and returns the commit.
+func checkoutLocked(
+	ctx context.Context,
+	syncer *templates.Syncer,
+	lock *templates.Lock,
+	template *templates.Template,
+	templateDir string,
+) (string, error) {
+	entry := lock.Find(template.Source)
+	if entry == nil {
+		return "", fmt.Errorf("template '%s' not found in lock file", template.Source)
+	}
+
+	if err := syncer.Checkout(ctx, templateDir, entry.Commit); err != nil {
+		return "", err
+	}
+
+	return entry.Commit, nil
+}
+
+// discrepanciesMarkdown lists the catalog discrepancies of every template.
+func discrepanciesMarkdown(allResults []*analyze.TemplateWithResults) string {
+	builder := strings.Builder{}
+
+	fmt.Fprintln(&builder)
+	fmt.Fprintln(&builder, "# Catalog Discrepancies")
+
+	for _, result := range allResults {
+		discrepancies := analyze.CatalogDiscrepancies(result.Analysis)
+		if len(discrepancies) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(&builder)
+		fmt.Fprintf(&builder, "## %s\n", result.Template.Title)
+		fmt.Fprintln(&builder)
+
+		for _, discrepancy := range discrepancies {
+			fmt.Fprintf(&builder, "- %s\n", discrepancy.Message)
+		}
+	}
+
+	return builder.String()
+}
+
+// baseImagesMarkdown lists the Dockerfile base images by the number of templates using them.
+func baseImagesMarkdown(allResults []*analyze.TemplateWithResults) string {
+	templateCounts := map[string]int{}
+
+	for _, result := range allResults {
+		seen := map[string]bool{}
+		for _, image := range analyze.BaseImages(result.Analysis) {
+			if !seen[image] {
+				seen[image] = true
+				templateCounts[image]++
+			}
+		}
+	}
+
+	images := []string{}
+	for image := range templateCounts {
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if templateCounts[images[i]] != templateCounts[images[j]] {
+			return templateCounts[images[i]] > templateCounts[images[j]]
+		}
+
+		return images[i] < images[j]
+	})
+
+	builder := strings.Builder{}
+
+	fmt.Fprintln(&builder)
+	fmt.Fprintln(&builder, "# Base Images")
+	fmt.Fprintln(&builder)
+
+	for _, image := range images {
+		fmt.Fprintf(&builder, "- `%s`: %d\n", image, templateCounts[image])
+	}
+
+	return builder.String()
 }
 
 func writeAnalysisToCsv(filePath string, allResults []*analyze.TemplateWithResults, segmentFilter string, recursive bool) (map[string]string, error) {
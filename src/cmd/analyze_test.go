@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wbreza/azd-template-analysis/analyze"
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+// writeTestCatalog writes a catalog of templates with hooks, container services and infra to the sync directory.
+func writeTestCatalog(t *testing.T, syncDir string, templateCount int) {
+	t.Helper()
+
+	templateList := []*templates.Template{}
+	analysisCtx := analyze.AnalysisContext{WorkingDirectory: syncDir}
+
+	for i := range templateCount {
+		template := &templates.Template{
+			Title:  fmt.Sprintf("Template %02d", i),
+			Source: fmt.Sprintf("https://github.com/contoso/template-%02d", i),
+			Tags:   []string{"bicep", "python", "aca"},
+		}
+		templateList = append(templateList, template)
+
+		templatePath, err := analysisCtx.TemplatePath(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := map[string]string{
+			"azure.yaml": fmt.Sprintf(`name: template-%02d
+hooks:
+  preprovision:
+    shell: sh
+    run: ./scripts/setup.sh
+  postdeploy:
+    - shell: sh
+      run: az account show
+    - shell: sh
+      run: azd env get-values
+services:
+  api:
+    project: ./src/api
+    language: python
+    host: containerapp
+  web:
+    project: ./src/web
+    language: js
+    host: appservice
+`, i),
+			"scripts/setup.sh":         "#!/bin/sh\naz login\n",
+			"src/api/Dockerfile":       fmt.Sprintf("FROM python:3.%d\nUSER app\nEXPOSE 8000\n", i),
+			"src/api/requirements.txt": "fastapi\n",
+			"src/web/package.json":     "{}\n",
+			"infra/main.bicep":         "",
+		}
+
+		// Vary the templates so every output file has differing rows
+		switch i % 3 {
+		case 1:
+			delete(files, "azure.yaml")
+		case 2:
+			files["infra/main.tf"] = ""
+			delete(files, "src/api/Dockerfile")
+		}
+
+		for name, contents := range files {
+			path := filepath.Join(templatePath, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	templateBytes, err := json.Marshal(templateList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(syncDir, "templates.json"), templateBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunAnalyzeParallelOutput(t *testing.T) {
+	syncDir := t.TempDir()
+	writeTestCatalog(t, syncDir, 12)
+
+	outputs := map[int]string{}
+	for _, parallel := range []int{1, 4} {
+		outputs[parallel] = t.TempDir()
+
+		err := runAnalyze(context.Background(), &analyzeFlags{
+			filePath:        syncDir,
+			outputDir:       outputs[parallel],
+			parallel:        parallel,
+			templateTimeout: time.Minute,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(outputs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) == 0 {
+		t.Fatal("expected output files")
+	}
+
+	for _, entry := range entries {
+		serial, err := os.ReadFile(filepath.Join(outputs[1], entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parallel, err := os.ReadFile(filepath.Join(outputs[4], entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(serial, parallel) {
+			t.Errorf("%s differs between the serial and parallel analysis", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wbreza/azd-template-analysis/templates"
+)
+
+func newCatalogCmd(root *cobra.Command) {
+	catalog := &cobra.Command{
+		Use:   "catalog",
+		Short: "Inspect template catalogs.",
+	}
+
+	newCatalogValidateCmd(catalog)
+	newCatalogDiffCmd(catalog)
+
+	root.AddCommand(catalog)
+}
+
+type catalogValidateFlags struct {
+	taxonomy string
+	format   string
+	strict   bool
+}
+
+func newCatalogValidateCmd(catalog *cobra.Command) {
+	flags := &catalogValidateFlags{}
+
+	validate := &cobra.Command{
+		Use:   "validate <catalog>",
+		Short: "Validate the templates listed in a catalog file or url.",
+		Args:  cobra.ExactArgs(1),
+		// Validation failures are reported by the command output
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format != "text" && flags.format != "json" {
+				return fmt.Errorf("unsupported format '%s', expected text or json", flags.format)
+			}
+
+			var taxonomy *templates.Taxonomy
+			if flags.taxonomy != "" {
+				var err error
+				taxonomy, err = templates.LoadTaxonomy(flags.taxonomy)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Templates are loaded from the source directly so duplicates are not merged
+			source, err := templates.NewCatalogSource(args[0], nil)
+			if err != nil {
+				return err
+			}
+
+			catalogTemplates, err := source.Templates(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to load catalog '%s': %w", source.Name(), err)
+			}
+
+			report := templates.ValidateCatalog(source.Name(), catalogTemplates, taxonomy)
+
+			if flags.format == "json" {
+				reportBytes, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal validation report: %w", err)
+				}
+
+				fmt.Println(string(reportBytes))
+			} else {
+				printValidationReport(report)
+			}
+
+			errorCount := report.Count(templates.SeverityError)
+			warningCount := report.Count(templates.SeverityWarning)
+			if errorCount > 0 || (flags.strict && warningCount > 0) {
+				return fmt.Errorf("catalog validation failed with %d errors and %d warnings", errorCount, warningCount)
+			}
+
+			return nil
+		},
+	}
+
+	validate.Flags().StringVar(&flags.taxonomy, "taxonomy", "", "Path to a YAML or JSON file listing the allowed tags, languages, frameworks, azureServices and IaC values.")
+	validate.Flags().StringVar(&flags.format, "format", "text", "Output format, text or json.")
+	validate.Flags().BoolVar(&flags.strict, "strict", false, "Fail when the catalog has warnings.")
+
+	catalog.AddCommand(validate)
+}
+
+type catalogDiffFlags struct {
+	format string
+}
+
+func newCatalogDiffCmd(catalog *cobra.Command) {
+	flags := &catalogDiffFlags{}
+
+	diff := &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "List the templates added, removed and modified between two templates.json snapshots.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format != "text" && flags.format != "markdown" && flags.format != "json" {
+				return fmt.Errorf("unsupported format '%s', expected text, markdown or json", flags.format)
+			}
+
+			oldTemplates, err := templates.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load templates: %w", err)
+			}
+
+			newTemplates, err := templates.Load(args[1])
+			if err != nil {
+				return fmt.Errorf("failed to load templates: %w", err)
+			}
+
+			catalogDiff := templates.DiffCatalogs(oldTemplates, newTemplates)
+
+			switch flags.format {
+			case "json":
+				diffBytes, err := json.MarshalIndent(catalogDiff, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal catalog diff: %w", err)
+				}
+
+				fmt.Println(string(diffBytes))
+			case "markdown":
+				fmt.Print(catalogDiff.Markdown())
+			default:
+				fmt.Print(catalogDiff.String())
+			}
+
+			return nil
+		},
+	}
+
+	diff.Flags().StringVar(&flags.format, "format", "text", "Output format, text, markdown or json.")
+
+	catalog.AddCommand(diff)
+}
+
+func printValidationReport(report *templates.ValidationReport) {
+	for _, issue := range report.Issues {
+		message := fmt.Sprintf("[%s] #%d '%s' %s: %s", issue.Rule, issue.Index, issue.Title, issue.Field, issue.Message)
+
+		if issue.Severity == templates.SeverityError {
+			color.Red("ERROR: %s", message)
+		} else {
+			color.Yellow("WARNING: %s", message)
+		}
+	}
+
+	summary := fmt.Sprintf(
+		"Validated %d templates in '%s': %d errors, %d warnings.",
+		report.Templates,
+		report.Catalog,
+		report.Count(templates.SeverityError),
+		report.Count(templates.SeverityWarning),
+	)
+
+	if len(report.Issues) == 0 {
+		color.Green("%s", summary)
+	} else {
+		fmt.Println(summary)
+	}
+}
@@ -1,14 +1,31 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+)
 
 func NewRootCmd() *cobra.Command {
 	root := &cobra.Command{
 		Use: "azdt",
+		// Errors are reported by main
+		SilenceErrors: true,
 	}
 
 	newSyncCmd(root)
 	newAnalyzeCmd(root)
+	newCatalogCmd(root)
 
 	return root
 }
+
+// withTimeout returns a context that is cancelled after the timeout, a timeout of zero or less never expires.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -15,6 +19,21 @@ import (
 type syncFlags struct {
 	outputDir string
 	template  string
+	catalogs  []string
+	fromLock  bool
+	prune     bool
+	dryRun    bool
+
+	timeout         time.Duration
+	templateTimeout time.Duration
+	retries         int
+	retryBackoff    time.Duration
+
+	cacheDir    string
+	offline     bool
+	httpTimeout time.Duration
+	httpRetries int
+	proxy       string
 }
 
 func newSyncCmd(root *cobra.Command) {
@@ -31,34 +50,156 @@ func newSyncCmd(root *cobra.Command) {
 				flags.outputDir = filepath.Join(cwd, "templates")
 			}
 
-			// Sync all templates
-			if flags.template == "" {
-				templateList, err := templates.GetTemplates("https://azure.github.io/awesome-azd/templates.json")
+			if flags.template != "" && (flags.prune || flags.fromLock) {
+				return fmt.Errorf("--prune and --from-lock cannot be combined with --template")
+			}
+
+			ctx := cmd.Context()
+			runCtx, cancel := withTimeout(ctx, flags.timeout)
+			defer cancel()
+
+			syncer := templates.NewSyncer(templates.NewGitCli())
+			lockFilePath := filepath.Join(flags.outputDir, templates.LockFileName)
+			reportFilePath := filepath.Join(flags.outputDir, templates.SyncReportFileName)
+			retryPolicy := templates.RetryPolicy{
+				MaxAttempts: flags.retries + 1,
+				Backoff:     flags.retryBackoff,
+			}
+
+			// Resolve the sources to sync from the lock file, the template flag or the catalogs
+			var templateList []*templates.Template
+			sources := []string{}
+			commits := map[string]string{}
+
+			switch {
+			case flags.fromLock:
+				lock, err := templates.LoadLock(lockFilePath)
 				if err != nil {
-					return fmt.Errorf("failed to get templates: %w", err)
+					return fmt.Errorf("failed to load lock file: %w", err)
+				}
+
+				for _, entry := range lock.Templates {
+					commits[entry.Source] = entry.Commit
+					sources = append(sources, entry.Source)
+				}
+			case flags.template != "":
+				sources = append(sources, flags.template)
+			default:
+				catalogOptions, err := newCatalogOptions(flags)
+				if err != nil {
+					return err
+				}
+
+				catalogSources := []templates.CatalogSource{}
+				for _, location := range flags.catalogs {
+					catalogSource, err := templates.NewCatalogSource(location, catalogOptions)
+					if err != nil {
+						return fmt.Errorf("failed to create catalog source: %w", err)
+					}
+
+					catalogSources = append(catalogSources, catalogSource)
 				}
 
-				var wg sync.WaitGroup
-				// Only allow 10 concurrent downloads
-				sem := make(chan bool, 10)
+				templateList, err = templates.LoadCatalogs(runCtx, catalogSources)
+				if err != nil {
+					return fmt.Errorf("failed to get templates: %w", err)
+				}
 
 				for _, t := range templateList {
-					wg.Add(1)
-					sem <- true
+					sources = append(sources, t.Source)
+				}
+			}
+
+			if flags.prune && len(sources) == 0 {
+				return fmt.Errorf("refusing to prune, no templates to sync")
+			}
+
+			if flags.dryRun {
+				plan, err := syncer.Plan(runCtx, flags.outputDir, sources, commits, flags.prune)
+				if err != nil {
+					return fmt.Errorf("failed to plan sync: %w", err)
+				}
+
+				printSyncPlan(plan)
+
+				return nil
+			}
+
+			if err := migrateLayout(runCtx, syncer, flags.outputDir, sources); err != nil {
+				return err
+			}
+
+			// Sync a specific template
+			if flags.template != "" {
+				templateCtx, cancelTemplate := withTimeout(runCtx, flags.templateTimeout)
+				defer cancelTemplate()
+
+				var entry *templates.LockEntry
+				attempts, err := templates.Retry(templateCtx, retryPolicy, func() error {
+					var err error
+					entry, err = syncer.Sync(templateCtx, flags.template, flags.outputDir)
+					return err
+				})
+
+				report, reportErr := templates.LoadSyncReport(reportFilePath)
+				if reportErr != nil {
+					report = templates.NewSyncReport()
+				}
+
+				report.Set(templates.NewSyncResult(flags.template, entry, attempts, err))
+				report.FinishedAt = time.Now().UTC()
+				if err := report.Save(reportFilePath); err != nil {
+					return err
+				}
+
+				if err != nil {
+					return fmt.Errorf("failed to sync template '%s' (%s): %w", flags.template, templates.ClassifyFailure(err), err)
+				}
+
+				color.Green("Template '%s' synced successfully.", flags.template)
+
+				lock, err := templates.LoadLock(lockFilePath)
+				if err != nil {
+					lock = templates.NewLock()
+				}
 
-					go func(source string) {
-						defer wg.Done()
-						defer func() { <-sem }()
+				lock.Set(entry)
 
-						if err := templates.Sync(source, flags.outputDir); err != nil {
-							color.Red("Template '%s' synced failed, %v.", source, err)
-						} else {
-							color.Green("Template '%s' synced successfully.", source)
-						}
-					}(t.Source)
+				return lock.Save(lockFilePath)
+			}
+
+			// Sync all templates, at the revisions recorded in the lock file when syncing from the lock
+			syncFunc := func(ctx context.Context, source string) (*templates.LockEntry, error) {
+				if flags.fromLock {
+					return syncer.SyncAt(ctx, source, flags.outputDir, commits[source])
 				}
 
-				wg.Wait()
+				return syncer.Sync(ctx, source, flags.outputDir)
+			}
+
+			report := templates.NewSyncReport()
+			entries, results := syncAll(runCtx, flags.templateTimeout, retryPolicy, sources, syncFunc)
+			report.Results = results
+
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("sync cancelled: %w", err)
+			}
+
+			if err := saveSyncReport(report, reportFilePath); err != nil {
+				return err
+			}
+
+			if !flags.fromLock {
+				// Templates that failed to sync keep the commit locked by a previous sync
+				lock, err := templates.LoadLock(lockFilePath)
+				if err != nil {
+					lock = templates.NewLock()
+				}
+
+				lock.Update(sources, entries)
+				if err := lock.Save(lockFilePath); err != nil {
+					return err
+				}
 
 				templateBytes, err := json.MarshalIndent(templateList, "", "  ")
 				if err != nil {
@@ -69,13 +210,28 @@ func newSyncCmd(root *cobra.Command) {
 				if err := os.WriteFile(templatesFilePath, templateBytes, 0644); err != nil {
 					return fmt.Errorf("failed to write templates file: %w", err)
 				}
+			}
 
-			} else { // Sync a specific template
-				if err := templates.Sync(flags.template, flags.outputDir); err != nil {
-					return fmt.Errorf("failed to sync template '%s': %w", flags.template, err)
+			if flags.prune {
+				plan, err := syncer.StaleClones(runCtx, flags.outputDir, sources)
+				if err != nil {
+					return fmt.Errorf("failed to find stale clones: %w", err)
 				}
 
-				color.Green("Template '%s' synced successfully.", flags.template)
+				for _, item := range plan.Items {
+					if item.Action == templates.SyncSkip {
+						color.Yellow("Template clone '%s' not removed: %s", item.Key, item.Note)
+					}
+				}
+
+				removed, err := plan.Prune(flags.outputDir)
+				for _, item := range removed {
+					color.Yellow("Template clone '%s' removed.", item.Key)
+				}
+
+				if err != nil {
+					return fmt.Errorf("failed to prune stale clones: %w", err)
+				}
 			}
 
 			return nil
@@ -84,6 +240,218 @@ func newSyncCmd(root *cobra.Command) {
 
 	sync.Flags().StringVarP(&flags.outputDir, "output", "o", "", "The output directory where templates will be downloaded.")
 	sync.Flags().StringVarP(&flags.template, "template", "t", "", "The specific git repo template to sync.")
+	sync.Flags().StringSliceVarP(
+		&flags.catalogs,
+		"catalog",
+		"c",
+		[]string{templates.DefaultCatalogUrl},
+		"The template catalogs to sync (http(s) url, file:// url or local path). Catalogs listed first take precedence.",
+	)
+	sync.Flags().BoolVar(&flags.fromLock, "from-lock", false, "Sync the templates at the exact commits recorded in the templates.lock file.")
+	sync.Flags().BoolVar(&flags.prune, "prune", false, "Remove template clones that are no longer listed in the catalogs or lock file.")
+	sync.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the planned clone, update, remove and unchanged actions without modifying the output directory.")
+	sync.Flags().StringVar(&flags.cacheDir, "cache-dir", "", "The directory where downloaded catalogs are cached (defaults to the user cache directory).")
+	sync.Flags().BoolVar(&flags.offline, "offline", false, "Use the cached catalogs instead of downloading them.")
+	sync.Flags().DurationVar(&flags.httpTimeout, "http-timeout", 30*time.Second, "The timeout of a single catalog download attempt (0 to disable).")
+	sync.Flags().IntVar(&flags.httpRetries, "http-retries", 2, "The number of times a catalog download is retried after a network or server error.")
+	sync.Flags().StringVar(&flags.proxy, "proxy", "", "The proxy url used to download catalogs (defaults to the HTTP_PROXY/HTTPS_PROXY environment variables).")
+	sync.Flags().DurationVar(&flags.timeout, "timeout", 0, "The maximum duration of the whole sync, templates not synced in time are reported as failed (0 to disable).")
+	sync.Flags().DurationVar(&flags.templateTimeout, "template-timeout", 10*time.Minute, "The maximum duration to sync a single template (0 to disable).")
+	sync.Flags().IntVar(&flags.retries, "retries", 2, "The number of times a template is retried after a transient (network) failure.")
+	sync.Flags().DurationVar(&flags.retryBackoff, "retry-backoff", 5*time.Second, "The delay before the first retry, doubled for every following retry.")
 
 	root.AddCommand(sync)
 }
+
+// newCatalogOptions creates the catalog download options from the sync flags.
+func newCatalogOptions(flags *syncFlags) (*templates.CatalogOptions, error) {
+	client, err := templates.NewHttpClient(templates.HttpOptions{
+		Timeout:      flags.httpTimeout,
+		Proxy:        flags.proxy,
+		Retries:      flags.httpRetries,
+		RetryBackoff: flags.retryBackoff,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	cacheDir := flags.cacheDir
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user cache directory: %w", err)
+		}
+
+		cacheDir = filepath.Join(userCacheDir, "azdt", "catalogs")
+	}
+
+	return &templates.CatalogOptions{
+		Client:   client,
+		CacheDir: cacheDir,
+		Offline:  flags.offline,
+	}, nil
+}
+
+// migrateLayout moves clones from the legacy flat sync directory layout into the owner-qualified layout.
+func migrateLayout(ctx context.Context, syncer *templates.Syncer, outputDir string, sources []string) error {
+	migrated, err := syncer.MigrateLayout(ctx, outputDir, sources)
+	for _, source := range migrated {
+		color.Yellow("Template '%s' moved to owner-qualified directory.", source)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to migrate sync directory layout: %w", err)
+	}
+
+	return nil
+}
+
+// printSyncPlan prints the planned action of every template repository and a summary.
+func printSyncPlan(plan *templates.SyncPlan) {
+	actionColors := map[templates.SyncAction]*color.Color{
+		templates.SyncClone:     color.New(color.FgGreen),
+		templates.SyncUpdate:    color.New(color.FgYellow),
+		templates.SyncRemove:    color.New(color.FgRed),
+		templates.SyncUnchanged: color.New(color.FgHiBlack),
+		templates.SyncSkip:      color.New(color.FgMagenta),
+	}
+
+	for _, item := range plan.Items {
+		line := fmt.Sprintf("%-10s %s", item.Action, item.Key)
+		if item.Note != "" {
+			line = fmt.Sprintf("%s (%s)", line, item.Note)
+		}
+
+		actionColors[item.Action].Println(line)
+	}
+
+	fmt.Printf(
+		"\nPlan: %d to clone, %d to update, %d to remove, %d skipped, %d unchanged.\n",
+		plan.Count(templates.SyncClone),
+		plan.Count(templates.SyncUpdate),
+		plan.Count(templates.SyncRemove),
+		plan.Count(templates.SyncSkip),
+		plan.Count(templates.SyncUnchanged),
+	)
+}
+
+// saveSyncReport writes the sync report and prints a summary of the failures.
+func saveSyncReport(report *templates.SyncReport, reportFilePath string) error {
+	report.FinishedAt = time.Now().UTC()
+	if err := report.Save(reportFilePath); err != nil {
+		return err
+	}
+
+	failures := report.Failures()
+	failureKinds := []string{}
+	failureCount := 0
+	for kind, count := range failures {
+		failureKinds = append(failureKinds, fmt.Sprintf("%s: %d", kind, count))
+		failureCount += count
+	}
+
+	slices.Sort(failureKinds)
+
+	if failureCount == 0 {
+		color.Green("Synced %d templates.", len(report.Results))
+	} else {
+		color.Yellow(
+			"Synced %d of %d templates, %d failed (%s).",
+			len(report.Results)-failureCount,
+			len(report.Results),
+			failureCount,
+			strings.Join(failureKinds, ", "),
+		)
+	}
+
+	return nil
+}
+
+// syncAll syncs the sources concurrently and returns the lock entries of the repositories that synced successfully
+// and the sync results of all repositories. Sources within the same repository are synced once, sources
+// referencing a different branch than the first source of their repository are reported as failed.
+// Each source is synced with its own timeout and transient failures are retried.
+// Sources not started before the context is done are reported as failed.
+func syncAll(
+	ctx context.Context,
+	templateTimeout time.Duration,
+	retryPolicy templates.RetryPolicy,
+	sources []string,
+	syncFunc func(ctx context.Context, source string) (*templates.LockEntry, error),
+) ([]*templates.LockEntry, []*templates.SyncResult) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	// Only allow 10 concurrent downloads
+	sem := make(chan bool, 10)
+	entries := []*templates.LockEntry{}
+	results := []*templates.SyncResult{}
+	repos := map[string]bool{}
+	conflicts := templates.RefConflicts(sources)
+
+	for _, source := range sources {
+		if err := conflicts[source]; err != nil {
+			color.Red("Template '%s' synced failed (%s), %v.", source, templates.FailureConflict, err)
+
+			mu.Lock()
+			results = append(results, templates.NewSyncResult(source, nil, 0, err))
+			mu.Unlock()
+
+			continue
+		}
+
+		repoKey := templates.RepoKey(source)
+		if repos[repoKey] {
+			continue
+		}
+
+		repos[repoKey] = true
+
+		select {
+		case sem <- true:
+		case <-ctx.Done():
+			color.Red("Template '%s' synced failed, %v.", source, ctx.Err())
+
+			mu.Lock()
+			results = append(results, templates.NewSyncResult(source, nil, 0, ctx.Err()))
+			mu.Unlock()
+
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(source string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			templateCtx, cancel := withTimeout(ctx, templateTimeout)
+			defer cancel()
+
+			var entry *templates.LockEntry
+			attempts, err := templates.Retry(templateCtx, retryPolicy, func() error {
+				var err error
+				entry, err = syncFunc(templateCtx, source)
+				return err
+			})
+
+			result := templates.NewSyncResult(source, entry, attempts, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			results = append(results, result)
+
+			if err != nil {
+				color.Red("Template '%s' synced failed (%s), %v.", source, result.Failure, err)
+				return
+			}
+
+			color.Green("Template '%s' synced successfully.", source)
+			entries = append(entries, entry)
+		}(source)
+	}
+
+	wg.Wait()
+
+	return entries, results
+}
@@ -0,0 +1,392 @@
+package dockerfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Instruction is a single Dockerfile instruction with its line continuations joined.
+type Instruction struct {
+	// Command is the upper case instruction name, i.e. FROM or RUN.
+	Command string `json:"command"`
+	// Args is the instruction text following the command.
+	Args string `json:"args"`
+	Line int    `json:"line"`
+}
+
+// Image is an image reference split into repository, tag and digest.
+type Image struct {
+	Reference  string `json:"reference"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	// Unresolved is set when the reference uses an ARG without a value, the reference is then kept unexpanded.
+	Unresolved bool `json:"unresolved,omitempty"`
+}
+
+// ParseImage splits an image reference such as mcr.microsoft.com/dotnet/aspnet:8.0 into its parts.
+func ParseImage(reference string) Image {
+	image := Image{
+		Reference:  reference,
+		Repository: reference,
+	}
+
+	if at := strings.Index(image.Repository, "@"); at >= 0 {
+		image.Digest = image.Repository[at+1:]
+		image.Repository = image.Repository[:at]
+	}
+
+	// The tag separator must follow the last path separator, registries may include a port
+	if colon := strings.LastIndex(image.Repository, ":"); colon > strings.LastIndex(image.Repository, "/") {
+		image.Tag = image.Repository[colon+1:]
+		image.Repository = image.Repository[:colon]
+	}
+
+	return image
+}
+
+// UsesLatest returns true when the image uses the latest tag explicitly or implicitly.
+// The tag of an unresolved reference is only known at build time.
+func (i Image) UsesLatest() bool {
+	if i.Unresolved {
+		return false
+	}
+
+	return i.Tag == "latest" || (i.Tag == "" && i.Digest == "" && i.Repository != "scratch")
+}
+
+// Stage is a build stage starting at a FROM instruction.
+type Stage struct {
+	Name     string `json:"name,omitempty"`
+	Image    Image  `json:"image"`
+	Platform string `json:"platform,omitempty"`
+	// BaseStage is the name of the previous stage the stage builds on, empty for external images.
+	BaseStage    string         `json:"baseStage,omitempty"`
+	Instructions []*Instruction `json:"instructions"`
+}
+
+// User returns the user set by the last USER instruction of the stage, empty when the stage does not set a user.
+func (s *Stage) User() string {
+	user := ""
+	for _, instruction := range s.Instructions {
+		if instruction.Command == "USER" {
+			user = instruction.Args
+		}
+	}
+
+	return user
+}
+
+// ExposedPorts returns the ports of the EXPOSE instructions of the stage.
+func (s *Stage) ExposedPorts() []string {
+	ports := []string{}
+	for _, instruction := range s.Instructions {
+		if instruction.Command == "EXPOSE" {
+			ports = append(ports, strings.Fields(instruction.Args)...)
+		}
+	}
+
+	return ports
+}
+
+type Dockerfile struct {
+	Stages []*Stage `json:"stages"`
+}
+
+// FinalStage returns the last build stage, which produces the image unless a build target is specified.
+func (d *Dockerfile) FinalStage() *Stage {
+	if len(d.Stages) == 0 {
+		return nil
+	}
+
+	return d.Stages[len(d.Stages)-1]
+}
+
+// Stage returns the build stage with the specified name.
+func (d *Dockerfile) Stage(name string) *Stage {
+	for _, stage := range d.Stages {
+		if stage.Name != "" && strings.EqualFold(stage.Name, name) {
+			return stage
+		}
+	}
+
+	return nil
+}
+
+// IsMultiStage returns true when the Dockerfile has more than one build stage.
+func (d *Dockerfile) IsMultiStage() bool {
+	return len(d.Stages) > 1
+}
+
+// BaseImages returns the external images the build stages start from, excluding references to other stages.
+func (d *Dockerfile) BaseImages() []Image {
+	images := []Image{}
+	for _, stage := range d.Stages {
+		if stage.BaseStage == "" {
+			images = append(images, stage.Image)
+		}
+	}
+
+	return images
+}
+
+// Load parses the Dockerfile at the path.
+func Load(path string) (*Dockerfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Dockerfile %s: %w", path, err)
+	}
+
+	defer file.Close()
+
+	dockerfile, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile %s: %w", path, err)
+	}
+
+	return dockerfile, nil
+}
+
+// Parse parses the build stages of a Dockerfile. ARG values declared before the first FROM are
+// substituted in the FROM image references, see expandArgs.
+func Parse(reader io.Reader) (*Dockerfile, error) {
+	instructions, err := parseInstructions(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerfile := &Dockerfile{
+		Stages: []*Stage{},
+	}
+
+	args := map[string]string{}
+
+	for _, instruction := range instructions {
+		if instruction.Command == "FROM" {
+			stage, err := newStage(instruction, args, dockerfile)
+			if err != nil {
+				return nil, err
+			}
+
+			dockerfile.Stages = append(dockerfile.Stages, stage)
+			continue
+		}
+
+		if len(dockerfile.Stages) == 0 {
+			if instruction.Command == "ARG" {
+				for _, arg := range strings.Fields(instruction.Args) {
+					name, value, _ := strings.Cut(arg, "=")
+					args[name] = strings.Trim(value, `"'`)
+				}
+			}
+
+			continue
+		}
+
+		stage := dockerfile.FinalStage()
+		stage.Instructions = append(stage.Instructions, instruction)
+	}
+
+	if len(dockerfile.Stages) == 0 {
+		return nil, fmt.Errorf("no FROM instruction found")
+	}
+
+	return dockerfile, nil
+}
+
+func newStage(instruction *Instruction, args map[string]string, dockerfile *Dockerfile) (*Stage, error) {
+	stage := &Stage{
+		Instructions: []*Instruction{},
+	}
+
+	fields := strings.Fields(instruction.Args)
+	reference := ""
+	resolved := true
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		switch {
+		case strings.HasPrefix(field, "--platform="):
+			stage.Platform = strings.TrimPrefix(field, "--platform=")
+		case strings.HasPrefix(field, "--"):
+			continue
+		case strings.EqualFold(field, "AS") && i+1 < len(fields):
+			stage.Name = fields[i+1]
+			i++
+		case reference == "":
+			reference, resolved = expandArgs(field, args)
+			if !resolved {
+				reference = field
+			}
+		}
+	}
+
+	if reference == "" {
+		return nil, fmt.Errorf("line %d: FROM instruction without image", instruction.Line)
+	}
+
+	if baseStage := dockerfile.Stage(reference); baseStage != nil {
+		stage.BaseStage = baseStage.Name
+	}
+
+	stage.Image = ParseImage(reference)
+	stage.Image.Unresolved = !resolved
+
+	return stage, nil
+}
+
+// expandArgs substitutes the ARG values referenced as $NAME, ${NAME}, ${NAME:-default} or ${NAME:+alternative}.
+// It returns false when an ARG without a value is referenced without a default.
+func expandArgs(text string, args map[string]string) (string, bool) {
+	var expanded strings.Builder
+	resolved := true
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 == len(text) {
+			expanded.WriteByte(text[i])
+			continue
+		}
+
+		name, operator, word := "", "", ""
+
+		if text[i+1] == '{' {
+			end := matchingBrace(text, i+1)
+			if end < 0 {
+				expanded.WriteString(text[i:])
+				break
+			}
+
+			name = text[i+2 : end]
+			if colon := strings.Index(name, ":"); colon >= 0 && colon+1 < len(name) && strings.ContainsRune("-+", rune(name[colon+1])) {
+				name, operator, word = name[:colon], name[colon:colon+2], name[colon+2:]
+			}
+
+			i = end
+		} else {
+			end := i + 1
+			for end < len(text) && isNameChar(text[end]) {
+				end++
+			}
+
+			if end == i+1 {
+				expanded.WriteByte(text[i])
+				continue
+			}
+
+			name = text[i+1 : end]
+			i = end - 1
+		}
+
+		value := args[name]
+
+		switch {
+		case operator == ":-" && value == "":
+			var wordResolved bool
+			value, wordResolved = expandArgs(word, args)
+			resolved = resolved && wordResolved
+		case operator == ":+" && value != "":
+			var wordResolved bool
+			value, wordResolved = expandArgs(word, args)
+			resolved = resolved && wordResolved
+		case operator == ":+":
+			value = ""
+		case value == "":
+			resolved = false
+		}
+
+		expanded.WriteString(value)
+	}
+
+	return expanded.String(), resolved
+}
+
+// matchingBrace returns the index of the brace closing the brace at the start index, -1 when it is not closed.
+func matchingBrace(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parseInstructions splits the Dockerfile into instructions, skipping comments and joining line continuations.
+func parseInstructions(reader io.Reader) ([]*Instruction, error) {
+	instructions := []*Instruction{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var current strings.Builder
+	startLine := 0
+	lineNumber := 0
+
+	flush := func() {
+		text := strings.TrimSpace(current.String())
+		current.Reset()
+
+		if text == "" {
+			return
+		}
+
+		command, args := text, ""
+		if space := strings.IndexAny(text, " \t"); space >= 0 {
+			command, args = text[:space], text[space+1:]
+		}
+
+		instructions = append(instructions, &Instruction{
+			Command: strings.ToUpper(command),
+			Args:    strings.TrimSpace(args),
+			Line:    startLine,
+		})
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if current.Len() == 0 {
+			if line == "" {
+				continue
+			}
+
+			startLine = lineNumber
+		}
+
+		if continued, found := strings.CutSuffix(line, `\`); found {
+			current.WriteString(continued)
+			current.WriteString(" ")
+			continue
+		}
+
+		current.WriteString(line)
+		flush()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return instructions, nil
+}
@@ -0,0 +1,121 @@
+package dockerfile
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const multiStageDockerfile = `# syntax=docker/dockerfile:1
+ARG NODE_VERSION=20
+
+FROM --platform=linux/amd64 node:${NODE_VERSION}-alpine AS build
+WORKDIR /app
+COPY package.json .
+RUN npm ci && \
+    npm run build
+
+FROM build AS test
+RUN npm test
+
+FROM nginx
+COPY --from=build /app/dist /usr/share/nginx/html
+USER root
+USER nginx
+EXPOSE 80 443/tcp
+`
+
+func TestParse(t *testing.T) {
+	dockerfile, err := Parse(strings.NewReader(multiStageDockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !dockerfile.IsMultiStage() || len(dockerfile.Stages) != 3 {
+		t.Fatalf("expected 3 stages, got %d", len(dockerfile.Stages))
+	}
+
+	build := dockerfile.Stages[0]
+	if build.Name != "build" || build.Platform != "linux/amd64" || build.Image.Repository != "node" || build.Image.Tag != "20-alpine" {
+		t.Errorf("unexpected build stage %+v", build)
+	}
+
+	run := build.Instructions[2]
+	if run.Command != "RUN" || run.Args != "npm ci &&  npm run build" || run.Line != 7 {
+		t.Errorf("unexpected continued instruction %+v", run)
+	}
+
+	if dockerfile.Stages[1].BaseStage != "build" {
+		t.Errorf("expected test stage to build on the build stage, got %+v", dockerfile.Stages[1])
+	}
+
+	final := dockerfile.FinalStage()
+	if final.User() != "nginx" || !slices.Equal(final.ExposedPorts(), []string{"80", "443/tcp"}) {
+		t.Errorf("unexpected final stage user %s and ports %v", final.User(), final.ExposedPorts())
+	}
+
+	baseImages := dockerfile.BaseImages()
+	if len(baseImages) != 2 || !baseImages[1].UsesLatest() || baseImages[0].UsesLatest() {
+		t.Errorf("unexpected base images %+v", baseImages)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		reference  string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{"mcr.microsoft.com/dotnet/aspnet:8.0", "mcr.microsoft.com/dotnet/aspnet", "8.0", ""},
+		{"localhost:5000/app", "localhost:5000/app", "", ""},
+		{"python@sha256:abc", "python", "", "sha256:abc"},
+		{"python:3.12-slim@sha256:abc", "python", "3.12-slim", "sha256:abc"},
+	}
+
+	for _, test := range tests {
+		image := ParseImage(test.reference)
+		if image.Repository != test.repository || image.Tag != test.tag || image.Digest != test.digest {
+			t.Errorf("%s: unexpected image %+v", test.reference, image)
+		}
+	}
+}
+
+func TestParseWithoutFrom(t *testing.T) {
+	if _, err := Parse(strings.NewReader("RUN echo hello\n")); err == nil {
+		t.Error("expected error for Dockerfile without FROM")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		dockerfile string
+		reference  string
+		usesLatest bool
+		unresolved bool
+	}{
+		{"ARG BASE=node:20\nFROM $BASE\n", "node:20", false, false},
+		{"FROM ${BASE:-node:18}\n", "node:18", false, false},
+		{"ARG BASE=python:3.12\nFROM ${BASE:-node:18}\n", "python:3.12", false, false},
+		{"ARG SUFFIX=slim\nFROM python:3.12${SUFFIX:+-$SUFFIX}\n", "python:3.12-slim", false, false},
+		{"FROM python:3.12${SUFFIX:+-$SUFFIX}\n", "python:3.12", false, false},
+		{"ARG REGISTRY=mcr.microsoft.com TAG=8.0\nFROM ${REGISTRY}/dotnet/aspnet:${TAG}\n", "mcr.microsoft.com/dotnet/aspnet:8.0", false, false},
+		{"ARG VERSION\nFROM node:${VERSION}\n", "node:${VERSION}", false, true},
+		{"ARG BASE\nFROM $BASE\n", "$BASE", false, true},
+		{"ARG VERSION\nFROM node:${VERSION:-latest}\n", "node:latest", true, false},
+		{"FROM node\n", "node", true, false},
+	}
+
+	for _, test := range tests {
+		dockerfile, err := Parse(strings.NewReader(test.dockerfile))
+		if err != nil {
+			t.Errorf("%q: %v", test.dockerfile, err)
+			continue
+		}
+
+		image := dockerfile.FinalStage().Image
+		if image.Reference != test.reference || image.UsesLatest() != test.usesLatest || image.Unresolved != test.unresolved {
+			t.Errorf("%q: unexpected image %+v, uses latest %v", test.dockerfile, image, image.UsesLatest())
+		}
+	}
+}
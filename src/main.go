@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/fatih/color"
 	"github.com/wbreza/azd-template-analysis/cmd"
@@ -19,9 +21,14 @@ func main() {
 		}
 	}
 
-	ctx := context.Background()
+	// Cancel running git operations and analysis on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	rootCmd := cmd.NewRootCmd()
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		color.Red("ERROR: %v", err)
+		// Errors are written to stderr so json output on stdout stays parsable
+		fmt.Fprintln(color.Error, color.RedString("ERROR: %v", err))
+		os.Exit(1)
 	}
 }
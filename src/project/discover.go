@@ -0,0 +1,75 @@
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// projectFileNames are the azure.yaml file names in order of preference.
+var projectFileNames = []string{"azure.yaml", "azure.yml"}
+
+// maxDiscoveryDepth is the deepest subdirectory searched for azure.yaml files.
+const maxDiscoveryDepth = 3
+
+// skipDirs are directories that never contain the azure.yaml of a template.
+var skipDirs = []string{"node_modules", "vendor", "bin", "obj"}
+
+// Discover returns the slash separated paths of the azure.yaml and azure.yml files within the template root,
+// ordered by preference: files closer to the root first, azure.yaml before azure.yml, then by path.
+func Discover(root string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path == root {
+				return nil
+			}
+
+			if strings.HasPrefix(entry.Name(), ".") || slices.Contains(skipDirs, entry.Name()) || depth(relativePath) > maxDiscoveryDepth {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if slices.Contains(projectFileNames, entry.Name()) {
+			files = append(files, filepath.ToSlash(relativePath))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover azure.yaml files in '%s': %w", root, err)
+	}
+
+	slices.SortFunc(files, func(a string, b string) int {
+		if depthA, depthB := depth(a), depth(b); depthA != depthB {
+			return depthA - depthB
+		}
+
+		if dirA, dirB := filepath.Dir(a), filepath.Dir(b); dirA != dirB {
+			return strings.Compare(dirA, dirB)
+		}
+
+		return slices.Index(projectFileNames, filepath.Base(a)) - slices.Index(projectFileNames, filepath.Base(b))
+	})
+
+	return files, nil
+}
+
+func depth(relativePath string) int {
+	return len(strings.Split(filepath.ToSlash(relativePath), "/"))
+}
@@ -0,0 +1,124 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location within azure.yaml formatted as file:line:column.
+type Position struct {
+	// File is the path of azure.yaml relative to the template root.
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func newPosition(file string, node *yaml.Node) Position {
+	return Position{
+		File:   file,
+		Line:   node.Line,
+		Column: node.Column,
+	}
+}
+
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+}
+
+// PositionError is an error at a location within azure.yaml.
+type PositionError struct {
+	Position Position
+	Err      error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Position, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// Position returns the location of the value at the key path, such as "services", "api", "project".
+// Sequence items are addressed by their index. It returns false when the path does not exist.
+func (p *Project) Position(path ...string) (Position, bool) {
+	if p.Node == nil {
+		return Position{File: p.File}, false
+	}
+
+	node := p.Node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, key := range path {
+		node = childNode(node, key)
+		if node == nil {
+			return Position{File: p.File}, false
+		}
+	}
+
+	return newPosition(p.File, node), true
+}
+
+func childNode(node *yaml.Node, key string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(key)
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index]
+		}
+	case yaml.AliasNode:
+		return childNode(node.Alias, key)
+	}
+
+	return nil
+}
+
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// positionErrors converts yaml parse and type errors into errors prefixed with the azure.yaml location.
+func positionErrors(file string, err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		positionErrs := []error{}
+		for _, message := range typeErr.Errors {
+			positionErrs = append(positionErrs, positionError(file, message))
+		}
+
+		return errors.Join(positionErrs...)
+	}
+
+	return positionError(file, err.Error())
+}
+
+func positionError(file string, message string) error {
+	matches := yamlErrorLine.FindStringSubmatch(message)
+	if matches == nil {
+		return &PositionError{Position: Position{File: file}, Err: errors.New(message)}
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+
+	return &PositionError{
+		Position: Position{File: file, Line: line},
+		Err:      errors.New(matches[2]),
+	}
+}
@@ -1,61 +1,265 @@
 package project
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Project is the azure.yaml project configuration.
 type Project struct {
-	Name      string                 `json:"name"`
-	Hooks     map[string]Hook        `json:"hooks"`
-	Workflows map[string]interface{} `json:"workflows"`
-	Metadata  *Metadata              `json:"metadata"`
-	Services  map[string]Service     `json:"services"`
-	Raw       string                 `json:"-"`
+	Name             string                  `yaml:"name" json:"name"`
+	ResourceGroup    string                  `yaml:"resourceGroup,omitempty" json:"resourceGroup,omitempty"`
+	Metadata         *Metadata               `yaml:"metadata,omitempty" json:"metadata"`
+	Infra            *Infra                  `yaml:"infra,omitempty" json:"infra,omitempty"`
+	Services         map[string]Service      `yaml:"services,omitempty" json:"services"`
+	Resources        map[string]Resource     `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Pipeline         *Pipeline               `yaml:"pipeline,omitempty" json:"pipeline,omitempty"`
+	Hooks            map[string]HookSequence `yaml:"hooks,omitempty" json:"hooks"`
+	RequiredVersions *RequiredVersions       `yaml:"requiredVersions,omitempty" json:"requiredVersions,omitempty"`
+	State            *State                  `yaml:"state,omitempty" json:"state,omitempty"`
+	Platform         *Platform               `yaml:"platform,omitempty" json:"platform,omitempty"`
+	Workflows        map[string]interface{}  `yaml:"workflows,omitempty" json:"workflows"`
+	Raw              string                  `yaml:"-" json:"-"`
+	// File is the slash separated path of the loaded azure.yaml relative to the template root.
+	File string `yaml:"-" json:"-"`
+	// Files are all the azure.yaml files found within the template, in order of preference.
+	Files []string `yaml:"-" json:"-"`
+	// Root is the directory of the loaded azure.yaml, service and hook paths are relative to it.
+	Root string `yaml:"-" json:"-"`
+	// Node is the parsed azure.yaml document used to resolve the position of any element.
+	Node *yaml.Node `yaml:"-" json:"-"`
 }
 
 type Metadata struct {
-	Template string `json:"template"`
+	Template string `yaml:"template" json:"template"`
+}
+
+// Infra configures the infrastructure provider and the location of the infrastructure as code.
+type Infra struct {
+	// Provider is bicep or terraform, empty for the default bicep provider.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"`
+	Module   string `yaml:"module,omitempty" json:"module,omitempty"`
+}
+
+// Pipeline configures the CI/CD pipeline created by azd pipeline config.
+type Pipeline struct {
+	// Provider is github or azdo.
+	Provider  string   `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Variables []string `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Secrets   []string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+}
+
+// RequiredVersions lists the version constraints of the tools required by the project.
+type RequiredVersions struct {
+	Azd string `yaml:"azd,omitempty" json:"azd,omitempty"`
+}
+
+// State configures where azd stores the environment state.
+type State struct {
+	Remote *RemoteState `yaml:"remote,omitempty" json:"remote,omitempty"`
+}
+
+type RemoteState struct {
+	Backend string         `yaml:"backend" json:"backend"`
+	Config  map[string]any `yaml:"config,omitempty" json:"config,omitempty"`
+}
+
+// Platform configures an alternate development platform such as Azure Dev Center.
+type Platform struct {
+	Type   string         `yaml:"type" json:"type"`
+	Config map[string]any `yaml:"config,omitempty" json:"config,omitempty"`
+}
+
+// Resource is an Azure resource composed by azd without infrastructure as code.
+type Resource struct {
+	Type string   `yaml:"type" json:"type"`
+	Uses []string `yaml:"uses,omitempty" json:"uses,omitempty"`
+	// Config holds the resource type specific properties.
+	Config map[string]any `yaml:",inline" json:"config,omitempty"`
 }
 
 type Hook struct {
-	Run     string `json:"run"`
-	Shell   string `json:"shell"`
-	Posix   *Hook  `json:"posix"`
-	Windows *Hook  `json:"windows"`
+	Run             string `yaml:"run" json:"run"`
+	Shell           string `yaml:"shell,omitempty" json:"shell"`
+	ContinueOnError bool   `yaml:"continueOnError,omitempty" json:"continueOnError,omitempty"`
+	Interactive     bool   `yaml:"interactive,omitempty" json:"interactive,omitempty"`
+	Posix           *Hook  `yaml:"posix,omitempty" json:"posix"`
+	Windows         *Hook  `yaml:"windows,omitempty" json:"windows"`
+	// Position is the location of the hook definition within azure.yaml.
+	Position Position `yaml:"-" json:"-"`
+}
+
+// HookSequence is a hook configured either as a single hook or as a list of hooks run in order.
+type HookSequence struct {
+	Entries []Hook
+	// IsList is set when the hook uses the list form, even with a single entry.
+	IsList bool
+}
+
+func (s *HookSequence) UnmarshalYAML(node *yaml.Node) error {
+	entryNodes := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		s.IsList = true
+		entryNodes = node.Content
+	}
+
+	s.Entries = make([]Hook, len(entryNodes))
+	for i, entryNode := range entryNodes {
+		if err := entryNode.Decode(&s.Entries[i]); err != nil {
+			return err
+		}
+
+		s.Entries[i].Position = newPosition("", entryNode)
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the hook in the form it was configured with.
+func (s HookSequence) MarshalJSON() ([]byte, error) {
+	if !s.IsList && len(s.Entries) == 1 {
+		return json.Marshal(s.Entries[0])
+	}
+
+	return json.Marshal(s.Entries)
 }
 
 type Service struct {
-	Host         string          `json:"host"`
-	Language     string          `json:"language"`
-	Hooks        map[string]Hook `json:"hooks"`
-	RelativePath string          `json:"project"`
+	ResourceName  string                  `yaml:"resourceName,omitempty" json:"resourceName,omitempty"`
+	ResourceGroup string                  `yaml:"resourceGroup,omitempty" json:"resourceGroup,omitempty"`
+	ApiVersion    string                  `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
+	Host          string                  `yaml:"host" json:"host"`
+	Language      string                  `yaml:"language" json:"language"`
+	RelativePath  string                  `yaml:"project" json:"project"`
+	Module        string                  `yaml:"module,omitempty" json:"module,omitempty"`
+	Image         string                  `yaml:"image,omitempty" json:"image,omitempty"`
+	Dist          string                  `yaml:"dist,omitempty" json:"dist,omitempty"`
+	Docker        *Docker                 `yaml:"docker,omitempty" json:"docker,omitempty"`
+	K8s           *K8s                    `yaml:"k8s,omitempty" json:"k8s,omitempty"`
+	Env           map[string]string       `yaml:"env,omitempty" json:"env,omitempty"`
+	Config        map[string]any          `yaml:"config,omitempty" json:"config,omitempty"`
+	Uses          []string                `yaml:"uses,omitempty" json:"uses,omitempty"`
+	Hooks         map[string]HookSequence `yaml:"hooks,omitempty" json:"hooks"`
 }
 
+// Docker configures how the container image of a service is built.
+type Docker struct {
+	Path        string   `yaml:"path,omitempty" json:"path,omitempty"`
+	Context     string   `yaml:"context,omitempty" json:"context,omitempty"`
+	Platform    string   `yaml:"platform,omitempty" json:"platform,omitempty"`
+	Target      string   `yaml:"target,omitempty" json:"target,omitempty"`
+	Registry    string   `yaml:"registry,omitempty" json:"registry,omitempty"`
+	Image       string   `yaml:"image,omitempty" json:"image,omitempty"`
+	Tag         string   `yaml:"tag,omitempty" json:"tag,omitempty"`
+	BuildArgs   []string `yaml:"buildArgs,omitempty" json:"buildArgs,omitempty"`
+	RemoteBuild bool     `yaml:"remoteBuild,omitempty" json:"remoteBuild,omitempty"`
+}
+
+// K8s configures how a service is deployed to AKS.
+type K8s struct {
+	DeploymentPath string       `yaml:"deploymentPath,omitempty" json:"deploymentPath,omitempty"`
+	Namespace      string       `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Deployment     *K8sResource `yaml:"deployment,omitempty" json:"deployment,omitempty"`
+	Service        *K8sResource `yaml:"service,omitempty" json:"service,omitempty"`
+	Ingress        *K8sIngress  `yaml:"ingress,omitempty" json:"ingress,omitempty"`
+	Helm           *Helm        `yaml:"helm,omitempty" json:"helm,omitempty"`
+	Kustomize      *Kustomize   `yaml:"kustomize,omitempty" json:"kustomize,omitempty"`
+}
+
+type K8sResource struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+type K8sIngress struct {
+	Name         string `yaml:"name,omitempty" json:"name,omitempty"`
+	RelativePath string `yaml:"relativePath,omitempty" json:"relativePath,omitempty"`
+}
+
+type Helm struct {
+	Repositories []HelmRepository `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+	Releases     []HelmRelease    `yaml:"releases,omitempty" json:"releases,omitempty"`
+}
+
+type HelmRepository struct {
+	Name string `yaml:"name" json:"name"`
+	Url  string `yaml:"url" json:"url"`
+}
+
+type HelmRelease struct {
+	Name      string `yaml:"name" json:"name"`
+	Chart     string `yaml:"chart" json:"chart"`
+	Version   string `yaml:"version,omitempty" json:"version,omitempty"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Values    string `yaml:"values,omitempty" json:"values,omitempty"`
+}
+
+type Kustomize struct {
+	Dir   string            `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Edits []string          `yaml:"edits,omitempty" json:"edits,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Load loads the azure.yaml file of the template. The file is discovered within the template root,
+// preferring azure.yaml at the root over azure.yml and files within subdirectories.
+// Parse errors are prefixed with their azure.yaml location.
 func Load(path string) (*Project, error) {
-	azureYamlPath := filepath.Join(path, "azure.yaml")
+	files, err := Discover(path)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := os.Stat(azureYamlPath)
-	if errors.Is(err, fs.ErrNotExist) {
-		return nil, fmt.Errorf("azure.yaml file not found in repo root @ %s, %w", azureYamlPath, err)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("azure.yaml file not found in repo @ %s, %w", path, fs.ErrNotExist)
 	}
 
+	fileName := files[0]
+	azureYamlPath := filepath.Join(path, filepath.FromSlash(fileName))
+
 	projectBytes, err := os.ReadFile(azureYamlPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read azure.yaml file %s: %w", azureYamlPath, err)
 	}
 
+	var node yaml.Node
+	if err := yaml.Unmarshal(projectBytes, &node); err != nil {
+		return nil, positionErrors(fileName, err)
+	}
+
 	var azdProject Project
-	if err := yaml.Unmarshal(projectBytes, &azdProject); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal azure.yaml file %s: %w", azureYamlPath, err)
+	if err := node.Decode(&azdProject); err != nil {
+		return nil, positionErrors(fileName, err)
 	}
 
 	azdProject.Raw = string(projectBytes)
+	azdProject.File = fileName
+	azdProject.Files = files
+	azdProject.Root = filepath.Dir(azureYamlPath)
+	azdProject.Node = &node
+
+	setHookFile(azdProject.Hooks, fileName)
+	for _, service := range azdProject.Services {
+		setHookFile(service.Hooks, fileName)
+	}
 
 	return &azdProject, nil
 }
+
+// IsNested returns true when azure.yaml is not located at the template root.
+func (p *Project) IsNested() bool {
+	return !slices.Contains(projectFileNames, p.File)
+}
+
+func setHookFile(hooks map[string]HookSequence, fileName string) {
+	for _, hookSequence := range hooks {
+		for i := range hookSequence.Entries {
+			hookSequence.Entries[i].Position.File = fileName
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadFullProject(t *testing.T) {
+	azdProject, err := Load(filepath.Join("testdata", "full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if azdProject.Infra == nil || azdProject.Infra.Provider != "terraform" || azdProject.Infra.Path != "deploy/infra" {
+		t.Errorf("unexpected infra %+v", azdProject.Infra)
+	}
+
+	if azdProject.Pipeline == nil || azdProject.Pipeline.Provider != "azdo" || len(azdProject.Pipeline.Secrets) != 1 {
+		t.Errorf("unexpected pipeline %+v", azdProject.Pipeline)
+	}
+
+	if azdProject.State == nil || azdProject.State.Remote == nil || azdProject.State.Remote.Backend != "AzureBlobStorage" {
+		t.Errorf("unexpected state %+v", azdProject.State)
+	}
+
+	if azdProject.RequiredVersions == nil || azdProject.RequiredVersions.Azd != ">= 1.10.0" {
+		t.Errorf("unexpected required versions %+v", azdProject.RequiredVersions)
+	}
+
+	if azdProject.Platform == nil || azdProject.Platform.Type != "devcenter" {
+		t.Errorf("unexpected platform %+v", azdProject.Platform)
+	}
+
+	web := azdProject.Resources["web"]
+	if web.Type != "host.containerapp" || len(web.Uses) != 1 || web.Config["port"] != 8080 {
+		t.Errorf("unexpected resource %+v", web)
+	}
+
+	api := azdProject.Services["api"]
+	if api.RelativePath != "src/api" || api.ApiVersion != "2024-02-02-preview" || api.Env["LOG_LEVEL"] != "debug" {
+		t.Errorf("unexpected api service %+v", api)
+	}
+
+	if api.Docker == nil || !api.Docker.RemoteBuild || api.Docker.Platform != "linux/amd64" {
+		t.Errorf("unexpected docker options %+v", api.Docker)
+	}
+
+	if azdProject.Services["web"].Dist != "build" || azdProject.Services["web"].Config["appLocation"] != "src/web" {
+		t.Errorf("unexpected web service %+v", azdProject.Services["web"])
+	}
+
+	k8s := azdProject.Services["aks"].K8s
+	if k8s == nil || k8s.Namespace != "todo" || k8s.Helm == nil || k8s.Helm.Releases[0].Chart != "bitnami/redis" {
+		t.Errorf("unexpected k8s options %+v", k8s)
+	}
+}
+
+func TestLoadHookSequences(t *testing.T) {
+	azdProject, err := Load(filepath.Join("testdata", "hooklist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preprovision := azdProject.Hooks["preprovision"]
+	if !preprovision.IsList || len(preprovision.Entries) != 2 {
+		t.Fatalf("expected list hook with 2 entries, got %+v", preprovision)
+	}
+
+	if !preprovision.Entries[0].Interactive || !preprovision.Entries[1].ContinueOnError {
+		t.Errorf("unexpected hook options %+v", preprovision.Entries)
+	}
+
+	postprovision := azdProject.Hooks["postprovision"]
+	if postprovision.IsList || len(postprovision.Entries) != 1 || postprovision.Entries[0].Run != "./scripts/post.sh" {
+		t.Errorf("expected single hook, got %+v", postprovision)
+	}
+
+	prepackage := azdProject.Services["api"].Hooks["prepackage"]
+	if !prepackage.IsList || len(prepackage.Entries) != 1 || prepackage.Entries[0].Posix.Run != "./build.sh" {
+		t.Errorf("expected list hook with os variants, got %+v", prepackage)
+	}
+
+	hookBytes, err := json.Marshal(azdProject.Hooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hooks map[string]any
+	if err := json.Unmarshal(hookBytes, &hooks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := hooks["preprovision"].([]any); !ok {
+		t.Errorf("expected list hook to marshal as a list, got %s", hookBytes)
+	}
+
+	if _, ok := hooks["postprovision"].(map[string]any); !ok {
+		t.Errorf("expected single hook to marshal as an object, got %s", hookBytes)
+	}
+}
+
+func TestProjectPositions(t *testing.T) {
+	azdProject, err := Load(filepath.Join("testdata", "hooklist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if position := azdProject.Hooks["preprovision"].Entries[1].Position.String(); position != "azure.yaml:7:7" {
+		t.Errorf("expected hook list entry at azure.yaml:7:7, got %s", position)
+	}
+
+	if position := azdProject.Hooks["postprovision"].Entries[0].Position.String(); position != "azure.yaml:11:5" {
+		t.Errorf("expected single hook at azure.yaml:11:5, got %s", position)
+	}
+
+	position, has := azdProject.Position("services", "api", "hooks", "prepackage", "0", "posix", "run")
+	if !has || position.String() != "azure.yaml:24:18" {
+		t.Errorf("expected posix run at azure.yaml:24:18, got %s", position)
+	}
+
+	if _, has := azdProject.Position("services", "web"); has {
+		t.Error("expected missing service to have no position")
+	}
+}
+
+func TestLoadErrorPosition(t *testing.T) {
+	_, err := Load(filepath.Join("testdata", "invalid"))
+
+	var positionErr *PositionError
+	if !errors.As(err, &positionErr) {
+		t.Fatalf("expected position error, got %v", err)
+	}
+
+	if positionErr.Position.String() != "azure.yaml:4" {
+		t.Errorf("expected error at azure.yaml:4, got %s", err)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	azdProject, err := Load(filepath.Join("testdata", "schema"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := []string{}
+	for _, violation := range azdProject.ValidateSchema() {
+		actual = append(actual, fmt.Sprintf("%s %s", violation.Kind, violation))
+	}
+
+	expected := []string{
+		"invalidValue azure.yaml:3:13: infra.provider: value 'pulumi' is not one of bicep, terraform",
+		"invalidValue azure.yaml:8:11: services.api.host: value 'containerapps' is not one of appservice, containerapp, function, springapp, staticwebapp, aks, ai.endpoint",
+		"invalidType azure.yaml:10:20: services.api.docker.remoteBuild: expected boolean but got string",
+		"missingProperty azure.yaml:12:5: services.web: missing required property 'host'",
+		"unknownProperty azure.yaml:15:3: hooks.postprovison: unknown property 'postprovison'",
+		"invalidValue azure.yaml:19:14: hooks.preprovision[0].shell: value 'bash' is not one of sh, pwsh",
+	}
+
+	if !slices.Equal(actual, expected) {
+		t.Errorf("unexpected violations\n%s", strings.Join(actual, "\n"))
+	}
+
+	validProject, err := Load(filepath.Join("testdata", "full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if violations := validProject.ValidateSchema(); len(violations) > 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+}
+
+func TestValidateSchemaEmptyDocument(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "azure.yaml"), []byte("# no properties\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	azdProject, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := []string{}
+	for _, violation := range azdProject.ValidateSchema() {
+		actual = append(actual, fmt.Sprintf("%s %s", violation.Kind, violation))
+	}
+
+	expected := []string{"missingProperty azure.yaml:1:1: .: missing required property 'name'"}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("unexpected violations\n%s", strings.Join(actual, "\n"))
+	}
+}
+
+func TestLoadNestedProject(t *testing.T) {
+	root := filepath.Join("testdata", "nested")
+
+	files, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(files, []string{"samples/other/azure.yaml", "src/app/azure.yml"}) {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	azdProject, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if azdProject.Name != "other" || !azdProject.IsNested() || azdProject.Root != filepath.Join(root, "samples", "other") {
+		t.Errorf("expected nested project samples/other, got %s in %s", azdProject.Name, azdProject.Root)
+	}
+
+	azdProject, err = Load(filepath.Join("testdata", "full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if azdProject.IsNested() || azdProject.File != "azure.yaml" {
+		t.Errorf("expected root project, got %s", azdProject.File)
+	}
+}
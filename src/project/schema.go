@@ -0,0 +1,309 @@
+package project
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the upstream azd schema (schemas/<version>/azure.yaml.json in Azure/azure-dev)
+// the embedded schema is reproduced from.
+const SchemaVersion = "v1.0"
+
+// schemaJson is a local reproduction of the azure.yaml JSON schema maintained by hand, so validation does not
+// require network access. It is not a verbatim copy of the upstream azd schema and may lag behind the properties
+// azd accepts.
+//
+//go:embed schema/azure.yaml.approx.json
+var schemaJson []byte
+
+type ViolationKind string
+
+const (
+	ViolationUnknownProperty ViolationKind = "unknownProperty"
+	ViolationMissingProperty ViolationKind = "missingProperty"
+	ViolationInvalidType     ViolationKind = "invalidType"
+	ViolationInvalidValue    ViolationKind = "invalidValue"
+)
+
+// SchemaViolation is a location within azure.yaml that does not conform to the azure.yaml schema.
+type SchemaViolation struct {
+	Position Position      `json:"position"`
+	Path     string        `json:"path"`
+	Kind     ViolationKind `json:"kind"`
+	Message  string        `json:"message"`
+}
+
+func (v *SchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Position, v.Path, v.Message)
+}
+
+// schema is the subset of JSON schema draft-07 used by the azure.yaml schema:
+// $ref, type, properties, additionalProperties, required, enum, items, anyOf, oneOf, allOf, minLength and pattern.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaTypes        `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Enum                 []any              `json:"enum"`
+	Items                *schema            `json:"items"`
+	AnyOf                []*schema          `json:"anyOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	AllOf                []*schema          `json:"allOf"`
+	MinLength            *int               `json:"minLength"`
+	Pattern              string             `json:"pattern"`
+	Definitions          map[string]*schema `json:"definitions"`
+
+	// disallow is set for the false boolean schema
+	disallow bool
+}
+
+func (s *schema) UnmarshalJSON(data []byte) error {
+	var allow bool
+	if err := json.Unmarshal(data, &allow); err == nil {
+		s.disallow = !allow
+		return nil
+	}
+
+	type schemaFields schema
+	return json.Unmarshal(data, (*schemaFields)(s))
+}
+
+// schemaTypes is the type keyword, either a single type or a list of types.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var azureYamlSchema = func() *schema {
+	var root schema
+	if err := json.Unmarshal(schemaJson, &root); err != nil {
+		panic(fmt.Sprintf("invalid azure.yaml schema approximation: %v", err))
+	}
+
+	return &root
+}()
+
+// ValidateSchema validates the azure.yaml document against the local approximation of the azure.yaml schema.
+// An empty document is validated as an empty object, reporting the missing required properties.
+func (p *Project) ValidateSchema() []*SchemaViolation {
+	validator := &schemaValidator{
+		root: azureYamlSchema,
+		file: p.File,
+	}
+
+	document := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}
+	if p.Node != nil && len(p.Node.Content) > 0 {
+		document = p.Node.Content[0]
+	}
+
+	return validator.validate(azureYamlSchema, document, "")
+}
+
+type schemaValidator struct {
+	root *schema
+	file string
+}
+
+func (v *schemaValidator) violation(node *yaml.Node, path string, kind ViolationKind, format string, args ...any) *SchemaViolation {
+	if path == "" {
+		path = "."
+	}
+
+	return &SchemaViolation{
+		Position: newPosition(v.file, node),
+		Path:     path,
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (v *schemaValidator) resolve(s *schema) *schema {
+	for s.Ref != "" {
+		name, found := strings.CutPrefix(s.Ref, "#/definitions/")
+		definition, has := v.root.Definitions[name]
+		if !found || !has {
+			panic(fmt.Sprintf("unsupported schema reference '%s'", s.Ref))
+		}
+
+		s = definition
+	}
+
+	return s
+}
+
+func (v *schemaValidator) validate(s *schema, node *yaml.Node, path string) []*SchemaViolation {
+	s = v.resolve(s)
+
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if s.disallow {
+		return []*SchemaViolation{v.violation(node, path, ViolationUnknownProperty, "property is not allowed")}
+	}
+
+	nodeType := yamlType(node)
+	if len(s.Type) > 0 && !typeMatches(s.Type, nodeType) {
+		return []*SchemaViolation{
+			v.violation(node, path, ViolationInvalidType, "expected %s but got %s", strings.Join(s.Type, " or "), nodeType),
+		}
+	}
+
+	violations := []*SchemaViolation{}
+
+	for _, allOf := range s.AllOf {
+		violations = append(violations, v.validate(allOf, node, path)...)
+	}
+
+	for _, anyOf := range [][]*schema{s.AnyOf, s.OneOf} {
+		if len(anyOf) > 0 {
+			violations = append(violations, v.validateAnyOf(anyOf, node, path)...)
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(value any) bool { return fmt.Sprint(value) == node.Value }) {
+		allowed := []string{}
+		for _, value := range s.Enum {
+			allowed = append(allowed, fmt.Sprint(value))
+		}
+
+		violations = append(violations, v.violation(node, path, ViolationInvalidValue,
+			"value '%s' is not one of %s", node.Value, strings.Join(allowed, ", ")))
+	}
+
+	if nodeType == "string" {
+		if s.MinLength != nil && utf8.RuneCountInString(node.Value) < *s.MinLength {
+			violations = append(violations, v.violation(node, path, ViolationInvalidValue,
+				"value '%s' is shorter than %d characters", node.Value, *s.MinLength))
+		}
+
+		if s.Pattern != "" {
+			if matched, err := regexp.MatchString(s.Pattern, node.Value); err == nil && !matched {
+				violations = append(violations, v.violation(node, path, ViolationInvalidValue,
+					"value '%s' does not match pattern '%s'", node.Value, s.Pattern))
+			}
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		violations = append(violations, v.validateObject(s, node, path)...)
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range node.Content {
+				violations = append(violations, v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return violations
+}
+
+func (v *schemaValidator) validateObject(s *schema, node *yaml.Node, path string) []*SchemaViolation {
+	violations := []*SchemaViolation{}
+	keys := map[string]bool{}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		keys[key] = true
+
+		propertyPath := key
+		if path != "" {
+			propertyPath = path + "." + key
+		}
+
+		if property, has := s.Properties[key]; has {
+			violations = append(violations, v.validate(property, valueNode, propertyPath)...)
+			continue
+		}
+
+		if s.AdditionalProperties == nil {
+			continue
+		}
+
+		if s.AdditionalProperties.disallow {
+			violations = append(violations, v.violation(keyNode, propertyPath, ViolationUnknownProperty, "unknown property '%s'", key))
+			continue
+		}
+
+		violations = append(violations, v.validate(s.AdditionalProperties, valueNode, propertyPath)...)
+	}
+
+	for _, required := range s.Required {
+		if !keys[required] {
+			violations = append(violations, v.violation(node, path, ViolationMissingProperty, "missing required property '%s'", required))
+		}
+	}
+
+	return violations
+}
+
+// validateAnyOf passes when any of the schemas pass. Otherwise the violations of the only schema matching
+// the node type are reported, or a single violation when the node type matches none or several schemas.
+func (v *schemaValidator) validateAnyOf(schemas []*schema, node *yaml.Node, path string) []*SchemaViolation {
+	typeMatched := [][]*SchemaViolation{}
+
+	for _, anyOf := range schemas {
+		violations := v.validate(anyOf, node, path)
+		if len(violations) == 0 {
+			return nil
+		}
+
+		resolved := v.resolve(anyOf)
+		if len(resolved.Type) == 0 || typeMatches(resolved.Type, yamlType(node)) {
+			typeMatched = append(typeMatched, violations)
+		}
+	}
+
+	if len(typeMatched) == 1 {
+		return typeMatched[0]
+	}
+
+	return []*SchemaViolation{v.violation(node, path, ViolationInvalidType, "value does not match any of the allowed forms")}
+}
+
+// yamlType returns the JSON schema type of the yaml node.
+func yamlType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return "boolean"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		if _, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return "number"
+		}
+	}
+
+	return "string"
+}
+
+func typeMatches(types schemaTypes, nodeType string) bool {
+	return slices.Contains(types, nodeType) || (nodeType == "integer" && slices.Contains(types, "number"))
+}
@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// catalogCache stores downloaded catalogs along with the validators used for conditional requests.
+type catalogCache struct {
+	dir string
+}
+
+type cachedCatalog struct {
+	Url          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Body         []byte    `json:"-"`
+}
+
+func newCatalogCache(dir string) *catalogCache {
+	return &catalogCache{
+		dir: dir,
+	}
+}
+
+// Get returns the cached catalog for the url or nil when the url is not cached.
+func (c *catalogCache) Get(url string) (*cachedCatalog, error) {
+	bodyPath, metaPath := c.paths(url)
+
+	metaBytes, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog cache %s: %w", metaPath, err)
+	}
+
+	var cached cachedCatalog
+	if err := json.Unmarshal(metaBytes, &cached); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal catalog cache %s: %w", metaPath, err)
+	}
+
+	cached.Body, err = os.ReadFile(bodyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog cache %s: %w", bodyPath, err)
+	}
+
+	return &cached, nil
+}
+
+func (c *catalogCache) Set(cached *cachedCatalog) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create catalog cache directory: %w", err)
+	}
+
+	cached.FetchedAt = time.Now().UTC()
+	bodyPath, metaPath := c.paths(cached.Url)
+
+	metaBytes, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal catalog cache: %w", err)
+	}
+
+	if err := os.WriteFile(bodyPath, cached.Body, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog cache %s: %w", bodyPath, err)
+	}
+
+	if err := os.WriteFile(metaPath, metaBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write catalog cache %s: %w", metaPath, err)
+	}
+
+	return nil
+}
+
+func (c *catalogCache) paths(url string) (string, string) {
+	hash := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(hash[:8])
+
+	return filepath.Join(c.dir, name+".json"), filepath.Join(c.dir, name+".meta.json")
+}
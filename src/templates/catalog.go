@@ -0,0 +1,237 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DefaultCatalogUrl = "https://azure.github.io/awesome-azd/templates.json"
+
+// CatalogSource is a location that provides a list of templates.
+type CatalogSource interface {
+	// Name returns the name recorded on each template loaded from the catalog.
+	Name() string
+	// Templates loads the templates listed in the catalog.
+	Templates(ctx context.Context) ([]*Template, error)
+}
+
+// CatalogOptions configures how remote catalogs are downloaded.
+type CatalogOptions struct {
+	// Client is the http client used to download catalogs, nil to use http.DefaultClient.
+	Client *http.Client
+	// CacheDir is the directory where downloaded catalogs are cached, empty to disable caching.
+	CacheDir string
+	// Offline loads remote catalogs from the cache without any requests.
+	Offline bool
+}
+
+// NewCatalogSource creates a catalog source for the specified location.
+// Supported locations are http(s) URLs, file:// URLs and local file paths.
+func NewCatalogSource(location string, options *CatalogOptions) (CatalogSource, error) {
+	if options == nil {
+		options = &CatalogOptions{}
+	}
+
+	if location == "" {
+		return nil, fmt.Errorf("catalog location is empty")
+	}
+
+	parsedUrl, err := url.Parse(location)
+	if err == nil {
+		switch strings.ToLower(parsedUrl.Scheme) {
+		case "http", "https":
+			return newHttpCatalogSource(location, options), nil
+		case "file":
+			path := parsedUrl.Path
+			if parsedUrl.Host != "" && parsedUrl.Host != "localhost" {
+				path = "//" + parsedUrl.Host + path
+			}
+
+			// file:///C:/path on windows
+			if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+				path = path[1:]
+			}
+
+			return newFileCatalogSource(location, filepath.FromSlash(path)), nil
+		}
+	}
+
+	return newFileCatalogSource(location, location), nil
+}
+
+// LoadCatalogs loads and merges the templates from all the catalog sources.
+// Templates are de-duplicated by source, the first catalog listing a template wins.
+func LoadCatalogs(ctx context.Context, sources []CatalogSource) ([]*Template, error) {
+	allTemplates := []*Template{}
+	seen := map[string]bool{}
+
+	for _, source := range sources {
+		catalogTemplates, err := source.Templates(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load catalog '%s': %w", source.Name(), err)
+		}
+
+		for _, template := range catalogTemplates {
+			key := SourceKey(template.Source)
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			template.Catalog = source.Name()
+			allTemplates = append(allTemplates, template)
+		}
+	}
+
+	return allTemplates, nil
+}
+
+type httpCatalogSource struct {
+	url     string
+	client  *http.Client
+	cache   *catalogCache
+	offline bool
+}
+
+func newHttpCatalogSource(url string, options *CatalogOptions) *httpCatalogSource {
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var cache *catalogCache
+	if options.CacheDir != "" {
+		cache = newCatalogCache(options.CacheDir)
+	}
+
+	return &httpCatalogSource{
+		url:     url,
+		client:  client,
+		cache:   cache,
+		offline: options.Offline,
+	}
+}
+
+func (h *httpCatalogSource) Name() string {
+	return h.url
+}
+
+// Templates downloads the catalog, sending conditional requests when the catalog is cached
+// so unchanged catalogs are served from the cache.
+func (h *httpCatalogSource) Templates(ctx context.Context) ([]*Template, error) {
+	var cached *cachedCatalog
+	if h.cache != nil {
+		var err error
+		cached, err = h.cache.Get(h.url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if h.offline {
+		if cached == nil {
+			return nil, fmt.Errorf("catalog is not cached, sync without --offline first")
+		}
+
+		return unmarshalTemplates(cached.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download templates: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		return unmarshalTemplates(cached.Body)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download templates: unexpected status %s", res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading response body: %w", err)
+	}
+
+	templates, err := unmarshalTemplates(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.cache != nil {
+		err := h.cache.Set(&cachedCatalog{
+			Url:          h.url,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			Body:         body,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return templates, nil
+}
+
+type fileCatalogSource struct {
+	name string
+	path string
+}
+
+func newFileCatalogSource(name string, path string) *fileCatalogSource {
+	return &fileCatalogSource{
+		name: name,
+		path: path,
+	}
+}
+
+func (f *fileCatalogSource) Name() string {
+	return f.name
+}
+
+func (f *fileCatalogSource) Templates(ctx context.Context) ([]*Template, error) {
+	templateBytes, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", f.path, err)
+	}
+
+	templates, err := unmarshalTemplates(templateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file %s: %w", f.path, err)
+	}
+
+	return templates, nil
+}
+
+func unmarshalTemplates(body []byte) ([]*Template, error) {
+	var templates []*Template
+	if err := json.Unmarshal(body, &templates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal templates: %w", err)
+	}
+
+	return templates, nil
+}
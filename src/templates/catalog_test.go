@@ -0,0 +1,157 @@
+package templates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testCatalog = `[{"title":"Todo","source":"https://github.com/Azure-Samples/todo-nodejs-mongo","tags":["msft"]}]`
+
+func newTestCatalogServer(t *testing.T, failures int) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	var requests atomic.Int32
+	var notModified atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testCatalog))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, &requests, &notModified
+}
+
+func loadTestCatalog(t *testing.T, url string, options *CatalogOptions) ([]*Template, error) {
+	t.Helper()
+
+	source, err := NewCatalogSource(url, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return LoadCatalogs(t.Context(), []CatalogSource{source})
+}
+
+func TestCatalogConditionalRequest(t *testing.T) {
+	server, requests, notModified := newTestCatalogServer(t, 0)
+	options := &CatalogOptions{
+		CacheDir: t.TempDir(),
+	}
+
+	for i := 0; i < 2; i++ {
+		templates, err := loadTestCatalog(t, server.URL, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(templates) != 1 || templates[0].Title != "Todo" || templates[0].Catalog != server.URL {
+			t.Fatalf("unexpected templates %+v", templates)
+		}
+	}
+
+	if requests.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", requests.Load())
+	}
+
+	if notModified.Load() != 1 {
+		t.Errorf("expected second request to be served from the cache, got %d not modified responses", notModified.Load())
+	}
+}
+
+func TestCatalogOffline(t *testing.T) {
+	server, requests, _ := newTestCatalogServer(t, 0)
+	cacheDir := t.TempDir()
+
+	_, err := loadTestCatalog(t, server.URL, &CatalogOptions{CacheDir: cacheDir, Offline: true})
+	if err == nil {
+		t.Fatal("expected error loading uncached catalog offline")
+	}
+
+	if _, err := loadTestCatalog(t, server.URL, &CatalogOptions{CacheDir: cacheDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Close()
+
+	templates, err := loadTestCatalog(t, server.URL, &CatalogOptions{CacheDir: cacheDir, Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(templates) != 1 {
+		t.Errorf("expected 1 cached template, got %d", len(templates))
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("expected offline loads to not send requests, got %d requests", requests.Load())
+	}
+}
+
+func TestCatalogRetries(t *testing.T) {
+	server, requests, _ := newTestCatalogServer(t, 2)
+
+	client, err := NewHttpClient(HttpOptions{
+		Timeout:      time.Second,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := loadTestCatalog(t, server.URL, &CatalogOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(templates) != 1 || requests.Load() != 3 {
+		t.Errorf("expected success after 3 requests, got %d templates after %d requests", len(templates), requests.Load())
+	}
+}
+
+func TestCatalogFileSources(t *testing.T) {
+	server, _, _ := newTestCatalogServer(t, 0)
+	catalogPath := filepath.Join("testdata", "catalog.json")
+
+	fileSource, err := NewCatalogSource(catalogPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpSource, err := NewCatalogSource(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadCatalogs(t.Context(), []CatalogSource{fileSource, httpSource})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The todo template listed in both catalogs is de-duplicated, the first catalog wins
+	if len(templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(templates))
+	}
+
+	for _, template := range templates {
+		if template.Catalog != catalogPath {
+			t.Errorf("expected template '%s' from catalog %s, got %s", template.Title, catalogPath, template.Catalog)
+		}
+	}
+}
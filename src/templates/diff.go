@@ -0,0 +1,296 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// FieldChange is the change of a single catalog field between two snapshots.
+// List fields record the added and removed values, other fields the old and new value.
+type FieldChange struct {
+	Field   string   `json:"field"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+func (c *FieldChange) String() string {
+	if c.Added != nil || c.Removed != nil {
+		changes := []string{}
+		for _, value := range c.Added {
+			changes = append(changes, "+"+value)
+		}
+		for _, value := range c.Removed {
+			changes = append(changes, "-"+value)
+		}
+
+		return fmt.Sprintf("%s: %s", c.Field, strings.Join(changes, ", "))
+	}
+
+	return fmt.Sprintf("%s: '%s' -> '%s'", c.Field, c.Old, c.New)
+}
+
+// TemplateChange lists the field changes of a template present in both snapshots.
+type TemplateChange struct {
+	Title   string         `json:"title"`
+	Source  string         `json:"source"`
+	Changes []*FieldChange `json:"changes"`
+}
+
+// CatalogDiff lists the templates added, removed and modified between two catalog snapshots.
+type CatalogDiff struct {
+	Added    []*Template       `json:"added"`
+	Removed  []*Template       `json:"removed"`
+	Modified []*TemplateChange `json:"modified"`
+}
+
+// DiffCatalogs compares two catalog snapshots. Templates are matched by id, then by title and then by source
+// so renamed and moved templates are reported as modified.
+func DiffCatalogs(oldTemplates []*Template, newTemplates []*Template) *CatalogDiff {
+	diff := &CatalogDiff{
+		Added:    []*Template{},
+		Removed:  []*Template{},
+		Modified: []*TemplateChange{},
+	}
+
+	matches := map[*Template]*Template{}
+	matched := map[*Template]bool{}
+
+	matchers := []func(template *Template) string{
+		func(template *Template) string { return template.Id },
+		func(template *Template) string { return strings.ToLower(strings.TrimSpace(template.Title)) },
+		func(template *Template) string { return SourceKey(template.Source) },
+	}
+
+	for _, matchKey := range matchers {
+		oldByKey := map[string]*Template{}
+		for _, template := range oldTemplates {
+			key := matchKey(template)
+			if _, has := oldByKey[key]; key != "" && !matched[template] && !has {
+				oldByKey[key] = template
+			}
+		}
+
+		for _, template := range newTemplates {
+			if matches[template] != nil {
+				continue
+			}
+
+			// Templates with different ids are never matched by title or source
+			oldTemplate, has := oldByKey[matchKey(template)]
+			if has && !matched[oldTemplate] && (oldTemplate.Id == "" || template.Id == "" || oldTemplate.Id == template.Id) {
+				matches[template] = oldTemplate
+				matched[oldTemplate] = true
+			}
+		}
+	}
+
+	for _, template := range newTemplates {
+		oldTemplate := matches[template]
+		if oldTemplate == nil {
+			diff.Added = append(diff.Added, template)
+			continue
+		}
+
+		if changes := diffTemplate(oldTemplate, template); len(changes) > 0 {
+			diff.Modified = append(diff.Modified, &TemplateChange{
+				Title:   template.Title,
+				Source:  template.Source,
+				Changes: changes,
+			})
+		}
+	}
+
+	for _, template := range oldTemplates {
+		if !matched[template] {
+			diff.Removed = append(diff.Removed, template)
+		}
+	}
+
+	return diff
+}
+
+func diffTemplate(oldTemplate *Template, newTemplate *Template) []*FieldChange {
+	changes := []*FieldChange{}
+
+	valueFields := []struct {
+		name     string
+		old, new string
+	}{
+		{"id", oldTemplate.Id, newTemplate.Id},
+		{"title", oldTemplate.Title, newTemplate.Title},
+		{"description", oldTemplate.Description, newTemplate.Description},
+		{"preview", oldTemplate.Preview, newTemplate.Preview},
+		{"website", oldTemplate.Website, newTemplate.Website},
+		{"author", oldTemplate.Author, newTemplate.Author},
+		{"authorUrl", oldTemplate.AuthorUrl, newTemplate.AuthorUrl},
+		{"source", oldTemplate.Source, newTemplate.Source},
+	}
+
+	for _, field := range valueFields {
+		if field.old != field.new {
+			changes = append(changes, &FieldChange{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+
+	listFields := []struct {
+		name     string
+		old, new []string
+	}{
+		{"tags", oldTemplate.Tags, newTemplate.Tags},
+		{"languages", oldTemplate.Languages, newTemplate.Languages},
+		{"frameworks", oldTemplate.Frameworks, newTemplate.Frameworks},
+		{"azureServices", oldTemplate.AzureServices, newTemplate.AzureServices},
+		{"IaC", oldTemplate.IaC, newTemplate.IaC},
+	}
+
+	for _, field := range listFields {
+		added := missingValues(field.new, field.old)
+		removed := missingValues(field.old, field.new)
+
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, &FieldChange{Field: field.name, Added: added, Removed: removed})
+		}
+	}
+
+	// Fields without a typed field are compared by their raw json value
+	extraFields := []string{}
+	for name := range oldTemplate.Extra {
+		extraFields = append(extraFields, name)
+	}
+	for name := range newTemplate.Extra {
+		if _, has := oldTemplate.Extra[name]; !has {
+			extraFields = append(extraFields, name)
+		}
+	}
+
+	slices.Sort(extraFields)
+
+	for _, name := range extraFields {
+		oldValue := oldTemplate.Extra[name]
+		newValue := newTemplate.Extra[name]
+
+		if !jsonEqual(oldValue, newValue) {
+			changes = append(changes, &FieldChange{Field: name, Old: string(compactJson(oldValue)), New: string(compactJson(newValue))})
+		}
+	}
+
+	return changes
+}
+
+// jsonEqual compares raw json values ignoring formatting and the order of object keys.
+// Invalid json values are compared byte for byte.
+func jsonEqual(a json.RawMessage, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var aValue, bValue any
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return bytes.Equal(compactJson(a), compactJson(b))
+	}
+
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// compactJson removes the insignificant whitespace of the raw json value, invalid values are returned as is.
+func compactJson(value json.RawMessage) []byte {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, value); err != nil {
+		return value
+	}
+
+	return buffer.Bytes()
+}
+
+// missingValues returns the values not contained in the other list.
+func missingValues(values []string, other []string) []string {
+	missing := []string{}
+	for _, value := range values {
+		if !slices.Contains(other, value) {
+			missing = append(missing, value)
+		}
+	}
+
+	return missing
+}
+
+func (d *CatalogDiff) String() string {
+	var builder strings.Builder
+	title := color.New(color.FgHiWhite)
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+	modified := color.New(color.FgYellow)
+	change := color.New(color.FgHiBlack)
+
+	title.Fprintf(&builder, "%d added, %d removed, %d modified\n", len(d.Added), len(d.Removed), len(d.Modified))
+
+	for _, template := range d.Added {
+		added.Fprintf(&builder, "+ %s (%s)\n", template.Title, template.Source)
+	}
+
+	for _, template := range d.Removed {
+		removed.Fprintf(&builder, "- %s (%s)\n", template.Title, template.Source)
+	}
+
+	for _, template := range d.Modified {
+		modified.Fprintf(&builder, "~ %s (%s)\n", template.Title, template.Source)
+		for _, fieldChange := range template.Changes {
+			change.Fprintf(&builder, "    %s\n", fieldChange)
+		}
+	}
+
+	return builder.String()
+}
+
+func (d *CatalogDiff) Markdown() string {
+	builder := strings.Builder{}
+
+	fmt.Fprintln(&builder, "# Catalog Changes")
+	fmt.Fprintln(&builder)
+	fmt.Fprintf(&builder, "%d added, %d removed, %d modified\n", len(d.Added), len(d.Removed), len(d.Modified))
+
+	if len(d.Added) > 0 {
+		fmt.Fprintln(&builder)
+		fmt.Fprintln(&builder, "## Added")
+		fmt.Fprintln(&builder)
+
+		for _, template := range d.Added {
+			fmt.Fprintf(&builder, "- **%s**: %s\n", template.Title, template.Source)
+		}
+	}
+
+	if len(d.Removed) > 0 {
+		fmt.Fprintln(&builder)
+		fmt.Fprintln(&builder, "## Removed")
+		fmt.Fprintln(&builder)
+
+		for _, template := range d.Removed {
+			fmt.Fprintf(&builder, "- **%s**: %s\n", template.Title, template.Source)
+		}
+	}
+
+	if len(d.Modified) > 0 {
+		fmt.Fprintln(&builder)
+		fmt.Fprintln(&builder, "## Modified")
+
+		for _, template := range d.Modified {
+			fmt.Fprintln(&builder)
+			fmt.Fprintf(&builder, "### %s\n", template.Title)
+			fmt.Fprintln(&builder)
+
+			for _, fieldChange := range template.Changes {
+				fmt.Fprintf(&builder, "- `%s`\n", fieldChange)
+			}
+		}
+	}
+
+	return builder.String()
+}
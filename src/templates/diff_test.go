@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDiffCatalogs(t *testing.T) {
+	oldTemplates := []*Template{
+		{Id: "alpha", Title: "Alpha", Source: "https://github.com/contoso/alpha", Tags: []string{"msft", "python"}},
+		{Title: "Beta", Source: "https://github.com/contoso/beta", Tags: []string{"community"}},
+		{Title: "Gamma", Source: "https://github.com/contoso/gamma", Tags: []string{"community"}},
+		{Title: "Epsilon", Source: "https://github.com/contoso/epsilon", Tags: []string{"community"}},
+	}
+
+	newTemplates := []*Template{
+		{Id: "alpha", Title: "Alpha Renamed", Source: "https://github.com/contoso/alpha", Tags: []string{"msft", "bicep"}},
+		{Title: "Beta", Source: "https://github.com/fabrikam/beta", Tags: []string{"community"}},
+		{Title: "Delta", Source: "https://github.com/contoso/delta", Tags: []string{"community"}},
+		{Title: "Epsilon", Source: "https://github.com/contoso/epsilon", Tags: []string{"community"}},
+	}
+
+	diff := DiffCatalogs(oldTemplates, newTemplates)
+
+	if len(diff.Added) != 1 || diff.Added[0].Title != "Delta" {
+		t.Errorf("expected Delta to be added, got %v", diff.Added)
+	}
+
+	if len(diff.Removed) != 1 || diff.Removed[0].Title != "Gamma" {
+		t.Errorf("expected Gamma to be removed, got %v", diff.Removed)
+	}
+
+	if len(diff.Modified) != 2 {
+		t.Fatalf("expected 2 modified templates, got %d", len(diff.Modified))
+	}
+
+	alpha := diff.Modified[0]
+	if len(alpha.Changes) != 2 || alpha.Changes[0].Field != "title" || alpha.Changes[1].Field != "tags" {
+		t.Fatalf("unexpected alpha changes %v", alpha.Changes)
+	}
+
+	if !slices.Equal(alpha.Changes[1].Added, []string{"bicep"}) || !slices.Equal(alpha.Changes[1].Removed, []string{"python"}) {
+		t.Errorf("unexpected tag changes %s", alpha.Changes[1])
+	}
+
+	beta := diff.Modified[1]
+	if len(beta.Changes) != 1 || beta.Changes[0].Field != "source" || beta.Changes[0].New != "https://github.com/fabrikam/beta" {
+		t.Errorf("unexpected beta changes %v", beta.Changes)
+	}
+}
+
+func TestDiffCatalogsExtraFormatting(t *testing.T) {
+	unmarshalTemplates := func(snapshot string) []*Template {
+		t.Helper()
+
+		var templates []*Template
+		if err := json.Unmarshal([]byte(snapshot), &templates); err != nil {
+			t.Fatal(err)
+		}
+
+		return templates
+	}
+
+	indented := unmarshalTemplates(`[
+  {
+    "title": "Alpha",
+    "source": "https://github.com/contoso/alpha",
+    "author": "Contoso",
+    "metadata": {
+      "stars": 10,
+      "languages": ["python", "bicep"]
+    }
+  }
+]`)
+	compact := unmarshalTemplates(`[{"title":"Alpha","source":"https://github.com/contoso/alpha","author":"Contoso",` +
+		`"metadata":{"languages":["python","bicep"],"stars":10}}]`)
+	changed := unmarshalTemplates(`[{"title":"Alpha","source":"https://github.com/contoso/alpha","author":"Contoso",` +
+		`"metadata":{"languages":["python","bicep"],"stars":11}}]`)
+
+	if diff := DiffCatalogs(indented, compact); len(diff.Modified) != 0 {
+		t.Errorf("expected formatting changes to be ignored, got %v", diff.Modified[0].Changes)
+	}
+
+	diff := DiffCatalogs(indented, changed)
+	if len(diff.Modified) != 1 || len(diff.Modified[0].Changes) != 1 {
+		t.Fatalf("expected a single metadata change, got %v", diff.Modified)
+	}
+
+	change := diff.Modified[0].Changes[0]
+	if change.Field != "metadata" || change.Old != `{"stars":10,"languages":["python","bicep"]}` {
+		t.Errorf("unexpected metadata change %+v", change)
+	}
+}
@@ -0,0 +1,186 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var ErrDirtyWorkTree = errors.New("working tree has local changes")
+
+// Git provides the git operations required to sync templates.
+type Git interface {
+	// Clone clones the repository at url into dir.
+	Clone(ctx context.Context, url string, dir string) error
+	// Fetch fetches the origin remote and refreshes the remote default branch.
+	Fetch(ctx context.Context, dir string) error
+	// Checkout checks out rev with a detached HEAD.
+	Checkout(ctx context.Context, dir string, rev string) error
+	// CheckoutBranch creates or resets the local branch to startPoint and checks it out.
+	CheckoutBranch(ctx context.Context, dir string, branch string, startPoint string) error
+	// RevParse resolves rev to a commit SHA.
+	RevParse(ctx context.Context, dir string, rev string) (string, error)
+	// Log returns up to count commits reachable from rev, newest first.
+	Log(ctx context.Context, dir string, rev string, count int) ([]*Commit, error)
+	// Status returns the paths with uncommitted changes.
+	Status(ctx context.Context, dir string) ([]string, error)
+	// DefaultBranch returns the default branch of the origin remote.
+	DefaultBranch(ctx context.Context, dir string) (string, error)
+	// RemoteUrl returns the url of the origin remote.
+	RemoteUrl(ctx context.Context, dir string) (string, error)
+	// LsRemote resolves ref on the origin remote to a commit SHA without fetching.
+	LsRemote(ctx context.Context, dir string, ref string) (string, error)
+}
+
+type Commit struct {
+	Sha         string    `json:"sha"`
+	Author      string    `json:"author"`
+	CommittedAt time.Time `json:"committedAt"`
+	Subject     string    `json:"subject"`
+}
+
+type gitCli struct {
+}
+
+// NewGitCli creates a Git implementation backed by the git command line.
+func NewGitCli() Git {
+	return &gitCli{}
+}
+
+func (g *gitCli) Clone(ctx context.Context, url string, dir string) error {
+	_, err := runGit(ctx, "", "clone", url, dir)
+	return err
+}
+
+func (g *gitCli) Fetch(ctx context.Context, dir string) error {
+	if _, err := runGit(ctx, dir, "fetch", "origin", "--prune"); err != nil {
+		return err
+	}
+
+	// Picks up default branch renames on the remote
+	_, err := runGit(ctx, dir, "remote", "set-head", "origin", "--auto")
+	return err
+}
+
+func (g *gitCli) Checkout(ctx context.Context, dir string, rev string) error {
+	_, err := runGit(ctx, dir, "checkout", "--detach", rev)
+	return err
+}
+
+func (g *gitCli) CheckoutBranch(ctx context.Context, dir string, branch string, startPoint string) error {
+	_, err := runGit(ctx, dir, "checkout", "-B", branch, startPoint)
+	return err
+}
+
+func (g *gitCli) RevParse(ctx context.Context, dir string, rev string) (string, error) {
+	return runGit(ctx, dir, "rev-parse", "--verify", "--quiet", fmt.Sprintf("%s^{commit}", rev))
+}
+
+func (g *gitCli) Log(ctx context.Context, dir string, rev string, count int) ([]*Commit, error) {
+	output, err := runGit(ctx, dir, "log", fmt.Sprintf("-%d", count), "--format=%H%x1f%an%x1f%cI%x1f%s", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []*Commit{}
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log output '%s'", line)
+		}
+
+		committedAt, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit date '%s': %w", fields[2], err)
+		}
+
+		commits = append(commits, &Commit{
+			Sha:         fields[0],
+			Author:      fields[1],
+			CommittedAt: committedAt.UTC(),
+			Subject:     fields[3],
+		})
+	}
+
+	return commits, nil
+}
+
+func (g *gitCli) Status(ctx context.Context, dir string) ([]string, error) {
+	output, err := runGit(ctx, dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) > 3 {
+			paths = append(paths, line[3:])
+		}
+	}
+
+	return paths, nil
+}
+
+func (g *gitCli) DefaultBranch(ctx context.Context, dir string) (string, error) {
+	ref, err := runGit(ctx, dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(ref, "origin/"), nil
+}
+
+func (g *gitCli) RemoteUrl(ctx context.Context, dir string) (string, error) {
+	return runGit(ctx, dir, "remote", "get-url", "origin")
+}
+
+func (g *gitCli) LsRemote(ctx context.Context, dir string, ref string) (string, error) {
+	output, err := runGit(ctx, dir, "ls-remote", "origin", ref)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("ref '%s' not found on remote", ref)
+	}
+
+	return fields[0], nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+
+	gitCmd := exec.CommandContext(ctx, "git", args...)
+	gitCmd.Dir = dir
+	gitCmd.Stderr = &stderr
+	// Never block on credential prompts
+	gitCmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	// Child processes (i.e. git-remote-https) may keep the pipes open after git is killed
+	gitCmd.WaitDelay = 5 * time.Second
+
+	output, err := gitCmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("git %s: %w", args[0], ctx.Err())
+		}
+
+		message := strings.TrimSpace(stderr.String())
+		if message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,116 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HttpOptions configures the http client used to download catalogs.
+type HttpOptions struct {
+	// Timeout is the timeout of a single request attempt, zero disables the timeout.
+	Timeout time.Duration
+	// Proxy is the url of the proxy server, empty to use the HTTP_PROXY/HTTPS_PROXY environment variables.
+	Proxy string
+	// Retries is the number of times a request is retried after a network error or server error response.
+	Retries int
+	// RetryBackoff is the delay before the first retry, doubled for every following retry.
+	RetryBackoff time.Duration
+}
+
+// NewHttpClient creates an http client with the specified timeout, proxy and retry options.
+func NewHttpClient(options HttpOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.Proxy != "" {
+		proxyUrl, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url '%s': %w", options.Proxy, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			base:    transport,
+			timeout: options.Timeout,
+			retries: options.Retries,
+			backoff: options.RetryBackoff,
+		},
+	}, nil
+}
+
+// retryTransport retries idempotent requests that fail with a network error or a server error response.
+type retryTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+	backoff := t.backoff
+
+	for attempt := 0; ; attempt++ {
+		res, err := t.roundTrip(req)
+		if !retryable || attempt >= t.retries || !shouldRetry(res, err) {
+			return res, err
+		}
+
+		if res != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		backoff *= 2
+	}
+}
+
+// roundTrip sends a single attempt with the per attempt timeout.
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout covers reading the body
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
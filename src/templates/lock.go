@@ -0,0 +1,113 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const LockFileName = "templates.lock"
+
+// Lock records the exact revision of every synced template so an analysis can be reproduced.
+type Lock struct {
+	CreatedAt time.Time    `json:"createdAt"`
+	Templates []*LockEntry `json:"templates"`
+}
+
+type LockEntry struct {
+	Key         string    `json:"key"`
+	Source      string    `json:"source"`
+	Commit      string    `json:"commit"`
+	Branch      string    `json:"branch"`
+	CommittedAt time.Time `json:"committedAt"`
+	SyncedAt    time.Time `json:"syncedAt"`
+}
+
+func NewLock() *Lock {
+	return &Lock{
+		CreatedAt: time.Now().UTC(),
+		Templates: []*LockEntry{},
+	}
+}
+
+func LoadLock(path string) (*Lock, error) {
+	lockBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file %s: %w", path, err)
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(lockBytes, &lock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lock file %s: %w", path, err)
+	}
+
+	// Lock files written before template keys were introduced
+	for _, entry := range lock.Templates {
+		if entry.Key == "" {
+			entry.Key = RepoKey(entry.Source)
+		}
+	}
+
+	return &lock, nil
+}
+
+func (l *Lock) Save(path string) error {
+	slices.SortFunc(l.Templates, func(a *LockEntry, b *LockEntry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	lockBytes, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+
+	if err := os.WriteFile(path, lockBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write lock file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Find returns the lock entry of the repository of the template source or nil when the repository is not locked.
+func (l *Lock) Find(source string) *LockEntry {
+	key := RepoKey(source)
+	for _, entry := range l.Templates {
+		if entry.Key == key {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Set adds or replaces the lock entry for the entry source.
+func (l *Lock) Set(entry *LockEntry) {
+	for i, existing := range l.Templates {
+		if existing.Key == entry.Key {
+			l.Templates[i] = entry
+			return
+		}
+	}
+
+	l.Templates = append(l.Templates, entry)
+}
+
+// Update replaces the lock entries with the entries of a sync of the sources. Sources without a new entry,
+// i.e. sources that failed to sync, keep their locked entry. Entries of repositories no longer synced are removed.
+func (l *Lock) Update(sources []string, entries []*LockEntry) {
+	keys := map[string]bool{}
+	for _, source := range sources {
+		keys[RepoKey(source)] = true
+	}
+
+	l.Templates = slices.DeleteFunc(l.Templates, func(entry *LockEntry) bool {
+		return !keys[entry.Key]
+	})
+
+	for _, entry := range entries {
+		l.Set(entry)
+	}
+}
@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockUpdatePartialFailure(t *testing.T) {
+	lockFilePath := filepath.Join(t.TempDir(), LockFileName)
+	sources := []string{
+		"https://github.com/org/synced",
+		"https://github.com/org/failed",
+	}
+
+	lock := NewLock()
+	lock.Update(append(sources, "https://github.com/org/removed"), []*LockEntry{
+		{Key: RepoKey(sources[0]), Source: sources[0], Commit: "aaa", Branch: "main"},
+		{Key: RepoKey(sources[1]), Source: sources[1], Commit: "bbb", Branch: "main"},
+		{Key: "github.com/org/removed", Source: "https://github.com/org/removed", Commit: "ccc", Branch: "main"},
+	})
+	if err := lock.Save(lockFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second sync only succeeds for the first source
+	lock, err := LoadLock(lockFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Update(sources, []*LockEntry{
+		{Key: RepoKey(sources[0]), Source: sources[0], Commit: "ddd", Branch: "main", SyncedAt: time.Now().UTC()},
+	})
+	if err := lock.Save(lockFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = LoadLock(lockFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lock.Templates) != 2 {
+		t.Fatalf("expected 2 lock entries, got %d", len(lock.Templates))
+	}
+
+	if entry := lock.Find(sources[0]); entry == nil || entry.Commit != "ddd" {
+		t.Errorf("expected synced template to be locked at the new commit, got %+v", entry)
+	}
+
+	if entry := lock.Find(sources[1]); entry == nil || entry.Commit != "bbb" {
+		t.Errorf("expected failed template to keep the locked commit, got %+v", entry)
+	}
+
+	if entry := lock.Find("https://github.com/org/removed"); entry != nil {
+		t.Errorf("expected entry of removed template to be dropped, got %+v", entry)
+	}
+}
@@ -0,0 +1,375 @@
+package templates
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type SyncAction string
+
+const (
+	SyncClone     SyncAction = "clone"
+	SyncUpdate    SyncAction = "update"
+	SyncRemove    SyncAction = "remove"
+	SyncUnchanged SyncAction = "unchanged"
+	// SyncSkip is a source that cannot be synced, i.e. an invalid source,
+	// or a stale clone kept because removing it would discard local changes
+	SyncSkip SyncAction = "skip"
+)
+
+// PlanItem is the action sync takes for a single repository clone.
+type PlanItem struct {
+	Key    string     `json:"key"`
+	Source string     `json:"source,omitempty"`
+	Dir    string     `json:"dir"`
+	Action SyncAction `json:"action"`
+	Note   string     `json:"note,omitempty"`
+}
+
+// SyncPlan lists the actions sync takes without touching the sync directory.
+type SyncPlan struct {
+	Items []*PlanItem `json:"items"`
+}
+
+// Count returns the number of plan items with the specified action.
+func (p *SyncPlan) Count(action SyncAction) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Plan resolves the action sync takes for every source repository without modifying the sync directory.
+// Commits maps sources to the locked commit when syncing from a lock file, otherwise the remote branch is
+// compared with the local clone. When prune is set clones not referenced by any source are planned for removal.
+func (s *Syncer) Plan(
+	ctx context.Context,
+	outputDir string,
+	sources []string,
+	commits map[string]string,
+	prune bool,
+) (*SyncPlan, error) {
+	plan := &SyncPlan{
+		Items: []*PlanItem{},
+	}
+
+	repos := map[string]*PlanItem{}
+	legacyDirs := map[string]bool{}
+	conflicts := RefConflicts(sources)
+
+	// Sources that cannot be synced are planned to be skipped, sync records them as failed and continues
+	skip := func(source string, err error) {
+		plan.Items = append(plan.Items, &PlanItem{
+			Key:    SourceKey(source),
+			Source: source,
+			Action: SyncSkip,
+			Note:   err.Error(),
+		})
+	}
+
+	for _, source := range sources {
+		ref, err := ParseSource(source)
+		if err != nil {
+			skip(source, err)
+			continue
+		}
+
+		if item, has := repos[ref.RepoKey()]; has {
+			if err := conflicts[source]; err != nil {
+				item.Note = joinNotes(item.Note, err.Error())
+			}
+
+			continue
+		}
+
+		repoDir, err := ref.RepoDir(outputDir)
+		if err != nil {
+			skip(source, err)
+			continue
+		}
+
+		item := &PlanItem{
+			Key:    ref.RepoKey(),
+			Source: source,
+			Dir:    repoDir,
+		}
+		repos[ref.RepoKey()] = item
+		plan.Items = append(plan.Items, item)
+
+		cloneDir := item.Dir
+		if legacyDir, has := s.legacyClone(ctx, outputDir, source); has {
+			legacyDirs[legacyDir] = true
+			cloneDir = legacyDir
+			item.Note = fmt.Sprintf("moved from %s", legacyDir)
+		}
+
+		if _, err := os.Stat(cloneDir); err != nil {
+			item.Action = SyncClone
+			continue
+		}
+
+		item.Action, item.Note = s.planUpdate(ctx, ref, cloneDir, commits[source], item.Note)
+	}
+
+	if prune {
+		stalePlan, err := s.StaleClones(ctx, outputDir, sources)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range stalePlan.Items {
+			if !legacyDirs[item.Dir] {
+				plan.Items = append(plan.Items, item)
+			}
+		}
+	}
+
+	slices.SortFunc(plan.Items, func(a *PlanItem, b *PlanItem) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return plan, nil
+}
+
+// planUpdate compares the local clone with the locked commit or remote branch.
+func (s *Syncer) planUpdate(ctx context.Context, ref *SourceRef, cloneDir string, commit string, note string) (SyncAction, string) {
+	addNote := func(message string) string {
+		return joinNotes(note, message)
+	}
+
+	if err := s.ensureClean(ctx, cloneDir); err != nil {
+		return SyncUpdate, addNote(err.Error())
+	}
+
+	head, err := s.git.RevParse(ctx, cloneDir, "HEAD")
+	if err != nil {
+		return SyncUpdate, addNote(fmt.Sprintf("failed to resolve local HEAD: %v", err))
+	}
+
+	target := commit
+	if target == "" {
+		target, err = s.remoteTarget(ctx, ref, cloneDir)
+		if err != nil {
+			return SyncUpdate, addNote(err.Error())
+		}
+	}
+
+	if strings.HasPrefix(head, target) || strings.HasPrefix(target, head) {
+		if note != "" {
+			return SyncUpdate, note
+		}
+
+		return SyncUnchanged, ""
+	}
+
+	return SyncUpdate, addNote(fmt.Sprintf("%s -> %s", shortSha(head), shortSha(target)))
+}
+
+// remoteTarget resolves the commit sync checks out for the source on the remote without fetching.
+// Refs are resolved as a branch first, then as a tag. Refs that are neither are assumed to be a commit.
+func (s *Syncer) remoteTarget(ctx context.Context, ref *SourceRef, cloneDir string) (string, error) {
+	if ref.Ref == "" {
+		target, err := s.git.LsRemote(ctx, cloneDir, "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve remote HEAD: %w", err)
+		}
+
+		return target, nil
+	}
+
+	remoteRefs := []string{
+		fmt.Sprintf("refs/heads/%s", ref.Ref),
+		// Annotated tags resolve to the tag object, the peeled ref resolves to the tagged commit
+		fmt.Sprintf("refs/tags/%s^{}", ref.Ref),
+		fmt.Sprintf("refs/tags/%s", ref.Ref),
+	}
+
+	for _, remoteRef := range remoteRefs {
+		if target, err := s.git.LsRemote(ctx, cloneDir, remoteRef); err == nil {
+			return target, nil
+		}
+	}
+
+	if !isCommitSha(ref.Ref) {
+		return "", fmt.Errorf("failed to resolve remote ref '%s'", ref.Ref)
+	}
+
+	return ref.Ref, nil
+}
+
+func isCommitSha(ref string) bool {
+	if len(ref) < 7 || len(ref) > 40 {
+		return false
+	}
+
+	_, err := hex.DecodeString(ref + strings.Repeat("0", len(ref)%2))
+
+	return err == nil
+}
+
+// StaleClones plans the removal of the template clones within the sync directory not referenced by any of the sources.
+// A clone is a template clone when its origin remote matches its owner-qualified location, or when it is a legacy flat
+// clone of a source repository. Other repositories within the sync directory are left alone and clones with local
+// changes are planned to be skipped.
+func (s *Syncer) StaleClones(ctx context.Context, outputDir string, sources []string) (*SyncPlan, error) {
+	plan := &SyncPlan{
+		Items: []*PlanItem{},
+	}
+
+	repos := map[string]bool{}
+	for _, source := range sources {
+		repos[RepoKey(source)] = true
+	}
+
+	clones, err := ListClones(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cloneDir := range clones {
+		key := cloneKey(outputDir, cloneDir)
+		if repos[key] || !s.isTemplateClone(ctx, key, cloneDir, repos) {
+			continue
+		}
+
+		item := &PlanItem{
+			Key:    key,
+			Dir:    cloneDir,
+			Action: SyncRemove,
+		}
+
+		if err := s.ensureClean(ctx, cloneDir); err != nil {
+			item.Action = SyncSkip
+			item.Note = err.Error()
+		}
+
+		plan.Items = append(plan.Items, item)
+	}
+
+	return plan, nil
+}
+
+// isTemplateClone reports whether the clone was created by sync, either in the owner-qualified layout
+// with an origin remote matching the clone location or in the legacy flat layout for one of the repos.
+func (s *Syncer) isTemplateClone(ctx context.Context, key string, cloneDir string, repos map[string]bool) bool {
+	remoteUrl, err := s.git.RemoteUrl(ctx, cloneDir)
+	if err != nil {
+		return false
+	}
+
+	remoteKey := RepoKey(remoteUrl)
+
+	switch len(strings.Split(key, "/")) {
+	case 3:
+		return remoteKey == key
+	case 1:
+		return repos[remoteKey]
+	default:
+		return false
+	}
+}
+
+// Prune removes the clones planned for removal along with parent directories left empty.
+func (p *SyncPlan) Prune(outputDir string) ([]*PlanItem, error) {
+	removed := []*PlanItem{}
+
+	for _, item := range p.Items {
+		if item.Action != SyncRemove {
+			continue
+		}
+
+		if err := os.RemoveAll(item.Dir); err != nil {
+			return removed, fmt.Errorf("failed to remove '%s': %w", item.Dir, err)
+		}
+
+		removed = append(removed, item)
+
+		// Remove empty owner and host directories
+		for dir := filepath.Dir(item.Dir); dir != outputDir && strings.HasPrefix(dir, outputDir); dir = filepath.Dir(dir) {
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// ListClones returns the git clones within the sync directory in either the owner-qualified
+// (<root>/<host>/<owner>/<repo>) or the legacy flat (<root>/<repo>) layout.
+func ListClones(root string) ([]string, error) {
+	clones := []string{}
+	maxDepth := 3
+
+	if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+		return clones, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() || path == root {
+			return nil
+		}
+
+		if hasDir(path, ".git") {
+			clones = append(clones, path)
+			return filepath.SkipDir
+		}
+
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") || len(strings.Split(relativePath, string(filepath.Separator))) >= maxDepth {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clones in '%s': %w", root, err)
+	}
+
+	return clones, nil
+}
+
+func cloneKey(root string, cloneDir string) string {
+	relativePath, err := filepath.Rel(root, cloneDir)
+	if err != nil {
+		return cloneDir
+	}
+
+	return strings.ToLower(filepath.ToSlash(relativePath))
+}
+
+func joinNotes(note string, message string) string {
+	if note == "" {
+		return message
+	}
+
+	return fmt.Sprintf("%s, %s", note, message)
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
@@ -0,0 +1,277 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const SyncReportFileName = "sync-report.json"
+
+type SyncStatus string
+
+const (
+	SyncSucceeded SyncStatus = "synced"
+	SyncFailed    SyncStatus = "failed"
+)
+
+// FailureKind classifies why a template failed to sync.
+type FailureKind string
+
+const (
+	FailureAuth      FailureKind = "auth"
+	FailureNotFound  FailureKind = "notFound"
+	FailureNetwork   FailureKind = "network"
+	FailureTimeout   FailureKind = "timeout"
+	FailureCancelled FailureKind = "cancelled"
+	FailureDirtyTree FailureKind = "dirtyTree"
+	FailureConflict  FailureKind = "refConflict"
+	FailureUnknown   FailureKind = "unknown"
+)
+
+// Transient returns true when retrying the sync may succeed.
+func (k FailureKind) Transient() bool {
+	return k == FailureNetwork
+}
+
+var failurePatterns = []struct {
+	kind     FailureKind
+	patterns []string
+}{
+	{
+		kind: FailureNotFound,
+		patterns: []string{
+			"repository not found",
+			"does not appear to be a git repository",
+			"does not exist",
+			"returned error: 404",
+		},
+	},
+	{
+		kind: FailureAuth,
+		patterns: []string{
+			"authentication failed",
+			"could not read username",
+			"could not read password",
+			"terminal prompts disabled",
+			"permission denied",
+			"returned error: 403",
+		},
+	},
+	{
+		kind: FailureNetwork,
+		patterns: []string{
+			"could not resolve host",
+			"failed to connect",
+			"couldn't connect to server",
+			"connection timed out",
+			"connection refused",
+			"connection reset",
+			"network is unreachable",
+			"operation timed out",
+			"the remote end hung up",
+			"early eof",
+			"rpc failed",
+			"gnutls_handshake",
+			"gnutls recv error",
+			"tls handshake",
+			"ssl_connect",
+			"ssl_read",
+			"ssl_error_syscall",
+			"returned error: 5",
+		},
+	},
+}
+
+// ClassifyFailure classifies a sync error from its cause and the git error output.
+func ClassifyFailure(err error) FailureKind {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.Is(err, context.Canceled):
+		return FailureCancelled
+	case errors.Is(err, ErrDirtyWorkTree):
+		return FailureDirtyTree
+	case errors.Is(err, ErrRefConflict):
+		return FailureConflict
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, failure := range failurePatterns {
+		for _, pattern := range failure.patterns {
+			if strings.Contains(message, pattern) {
+				return failure.kind
+			}
+		}
+	}
+
+	return FailureUnknown
+}
+
+// RetryPolicy controls how transient sync failures are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every following retry.
+	Backoff time.Duration
+}
+
+// Retry runs the function until it succeeds, fails with a non transient failure,
+// the attempts are exhausted or the context is done. It returns the number of attempts made.
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) (int, error) {
+	backoff := policy.Backoff
+	attempt := 0
+
+	for {
+		attempt++
+
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !ClassifyFailure(err).Transient() {
+			return attempt, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, err
+		}
+
+		backoff *= 2
+	}
+}
+
+// SyncReport records the outcome of syncing every template repository.
+type SyncReport struct {
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Results    []*SyncResult `json:"results"`
+}
+
+type SyncResult struct {
+	Key      string      `json:"key"`
+	Source   string      `json:"source"`
+	Status   SyncStatus  `json:"status"`
+	Failure  FailureKind `json:"failure,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Attempts int         `json:"attempts"`
+	Commit   string      `json:"commit,omitempty"`
+}
+
+// NewSyncResult creates the sync result of the source from the lock entry or error of the last attempt.
+func NewSyncResult(source string, entry *LockEntry, attempts int, err error) *SyncResult {
+	result := &SyncResult{
+		Key:      RepoKey(source),
+		Source:   source,
+		Status:   SyncSucceeded,
+		Attempts: attempts,
+	}
+
+	if err != nil {
+		result.Status = SyncFailed
+		result.Failure = ClassifyFailure(err)
+		result.Error = err.Error()
+	} else if entry != nil {
+		result.Commit = entry.Commit
+	}
+
+	return result
+}
+
+func NewSyncReport() *SyncReport {
+	return &SyncReport{
+		StartedAt: time.Now().UTC(),
+		Results:   []*SyncResult{},
+	}
+}
+
+func LoadSyncReport(path string) (*SyncReport, error) {
+	reportBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync report %s: %w", path, err)
+	}
+
+	var report SyncReport
+	if err := json.Unmarshal(reportBytes, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sync report %s: %w", path, err)
+	}
+
+	return &report, nil
+}
+
+func (r *SyncReport) Save(path string) error {
+	slices.SortFunc(r.Results, func(a *SyncResult, b *SyncResult) int {
+		if a.Key != b.Key {
+			return strings.Compare(a.Key, b.Key)
+		}
+
+		return strings.Compare(a.Source, b.Source)
+	})
+
+	reportBytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync report: %w", err)
+	}
+
+	if err := os.WriteFile(path, reportBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write sync report %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Find returns the sync result of the template source or of its repository,
+// or nil when the repository was not synced.
+func (r *SyncReport) Find(source string) *SyncResult {
+	if i := r.index(source); i >= 0 {
+		return r.Results[i]
+	}
+
+	return nil
+}
+
+// Set adds or replaces the sync result for the result source or repository.
+// Branch conflicts are recorded for the conflicting source without replacing the result of the repository.
+func (r *SyncReport) Set(result *SyncResult) {
+	i := slices.IndexFunc(r.Results, func(existing *SyncResult) bool { return existing.Source == result.Source })
+	if i < 0 && result.Failure != FailureConflict {
+		i = r.index(result.Source)
+	}
+
+	if i >= 0 {
+		r.Results[i] = result
+		return
+	}
+
+	r.Results = append(r.Results, result)
+}
+
+// index returns the index of the result of the source, falling back to the first result of the source repository.
+// Sources conflicting with the synced branch of their repository have a result of their own.
+func (r *SyncReport) index(source string) int {
+	if i := slices.IndexFunc(r.Results, func(result *SyncResult) bool { return result.Source == source }); i >= 0 {
+		return i
+	}
+
+	key := RepoKey(source)
+
+	return slices.IndexFunc(r.Results, func(result *SyncResult) bool { return result.Key == key })
+}
+
+// Failures returns the number of failed repositories by failure kind.
+func (r *SyncReport) Failures() map[FailureKind]int {
+	failures := map[FailureKind]int{}
+	for _, result := range r.Results {
+		if result.Status == SyncFailed {
+			failures[result.Failure]++
+		}
+	}
+
+	return failures
+}
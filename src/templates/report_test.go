@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected FailureKind
+	}{
+		{fmt.Errorf("git clone: %w", context.DeadlineExceeded), FailureTimeout},
+		{fmt.Errorf("git clone: %w", context.Canceled), FailureCancelled},
+		{fmt.Errorf("%w: azure.yaml", ErrDirtyWorkTree), FailureDirtyTree},
+		{fmt.Errorf("%w: branch 'dev'", ErrRefConflict), FailureConflict},
+		{errors.New("remote: Repository not found.\nfatal: repository 'https://github.com/a/b/' not found"), FailureNotFound},
+		{errors.New("fatal: could not read Username for 'https://github.com': terminal prompts disabled"), FailureAuth},
+		{errors.New("fatal: unable to access 'https://github.com/a/b/': Could not resolve host: github.com"), FailureNetwork},
+		{errors.New("error: RPC failed; curl 92 HTTP/2 stream 0 was not closed cleanly"), FailureNetwork},
+		{errors.New("fatal: unable to access 'https://github.com/a/b/': gnutls_handshake() failed: The TLS connection was non-properly terminated."), FailureNetwork},
+		{errors.New("fatal: unable to access 'https://github.com/a/b/': OpenSSL SSL_connect: SSL_ERROR_SYSCALL in connection to github.com:443"), FailureNetwork},
+		{errors.New("fatal: unable to access 'https://github.com/a/b/': net/http: TLS handshake timeout"), FailureNetwork},
+		{errors.New("fatal: remote error: upload-pack: not our ref in https://github.com/contoso/ssl-tls-demo"), FailureUnknown},
+		{errors.New("fatal: bad object"), FailureUnknown},
+	}
+
+	for _, test := range tests {
+		if actual := ClassifyFailure(test.err); actual != test.expected {
+			t.Errorf("ClassifyFailure(%q) = %s, expected %s", test.err, actual, test.expected)
+		}
+	}
+}
+
+func TestSyncReportFind(t *testing.T) {
+	report := NewSyncReport()
+	report.Set(NewSyncResult("https://github.com/org/repo/tree/main/templates/a", &LockEntry{Commit: "abc"}, 1, nil))
+	report.Set(NewSyncResult("https://github.com/org/repo/tree/dev/templates/b", nil, 0, fmt.Errorf("%w: dev", ErrRefConflict)))
+
+	if len(report.Results) != 2 {
+		t.Fatalf("expected a result for the conflicting source, got %d results", len(report.Results))
+	}
+
+	if result := report.Find("https://github.com/org/repo/tree/dev/templates/b"); result == nil || result.Status != SyncFailed {
+		t.Errorf("expected the conflicting source to be failed, got %+v", result)
+	}
+
+	if result := report.Find("https://github.com/org/repo/tree/main/templates/c"); result == nil || result.Commit != "abc" {
+		t.Errorf("expected the result of the repository, got %+v", result)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	calls := 0
+	attempts, err := Retry(t.Context(), policy, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("fatal: unable to access: Connection reset by peer")
+		}
+
+		return nil
+	})
+
+	if err != nil || attempts != 2 {
+		t.Errorf("expected success after 2 attempts, got %d attempts and error %v", attempts, err)
+	}
+
+	attempts, err = Retry(t.Context(), policy, func() error {
+		return errors.New("remote: Repository not found.")
+	})
+
+	if err == nil || attempts != 1 {
+		t.Errorf("expected non transient failure to not be retried, got %d attempts and error %v", attempts, err)
+	}
+
+	attempts, err = Retry(t.Context(), policy, func() error {
+		return errors.New("Could not resolve host: github.com")
+	})
+
+	if err == nil || attempts != 3 {
+		t.Errorf("expected transient failure to be retried 3 times, got %d attempts and error %v", attempts, err)
+	}
+}
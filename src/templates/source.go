@@ -0,0 +1,311 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const localHost = "local"
+
+// ErrRefConflict is returned for a source referencing a different branch of a repository than an earlier source.
+var ErrRefConflict = errors.New("repository is synced at a different branch")
+
+// SourceRef is a parsed template source that identifies a git repository
+// and optionally a branch and subdirectory within the repository.
+type SourceRef struct {
+	Host  string
+	Owner string
+	Repo  string
+	// Ref is the branch, tag or commit referenced by tree/blob urls, empty for the default branch.
+	// The first segment after tree/blob is the branch, branch names containing a slash are not supported:
+	// tree/feature/x/templates references branch feature and path x/templates.
+	Ref string
+	// Path is the slash separated template directory within the repository, empty for the repo root.
+	Path string
+	// CloneUrl is the url or path passed to git clone.
+	CloneUrl string
+}
+
+// ParseSource parses a template source such as https://github.com/Azure-Samples/todo-nodejs-mongo,
+// https://github.com/org/repo/tree/main/templates/foo, git@github.com:Azure-Samples/todo-nodejs-mongo.git
+// or a local repository path.
+func ParseSource(source string) (*SourceRef, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("template source is empty")
+	}
+
+	// scp-like syntax, i.e. git@github.com:owner/repo.git
+	if !strings.Contains(source, "://") {
+		if at := strings.Index(source, "@"); at >= 0 {
+			if colon := strings.Index(source[at:], ":"); colon > 0 {
+				host := source[at+1 : at+colon]
+				ref, _, err := newSourceRef(host, source[at+colon+1:])
+				if err != nil {
+					return nil, err
+				}
+
+				ref.CloneUrl = source
+				return ref, nil
+			}
+		}
+
+		return newLocalSourceRef(source)
+	}
+
+	parsedUrl, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template source '%s': %w", source, err)
+	}
+
+	switch strings.ToLower(parsedUrl.Scheme) {
+	case "http", "https", "ssh", "git":
+		if parsedUrl.Host == "" {
+			return nil, fmt.Errorf("invalid template source '%s': missing host", source)
+		}
+
+		ref, repoPath, err := newSourceRef(parsedUrl.Hostname(), parsedUrl.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template source '%s': %w", source, err)
+		}
+
+		ref.CloneUrl = fmt.Sprintf("%s://%s/%s", parsedUrl.Scheme, parsedUrl.Host, repoPath)
+		if parsedUrl.User != nil {
+			ref.CloneUrl = fmt.Sprintf("%s://%s@%s/%s", parsedUrl.Scheme, parsedUrl.User.String(), parsedUrl.Host, repoPath)
+		}
+
+		return ref, nil
+	case "file":
+		return newLocalSourceRef(filepath.FromSlash(parsedUrl.Path))
+	default:
+		return nil, fmt.Errorf("invalid template source '%s': unsupported scheme '%s'", source, parsedUrl.Scheme)
+	}
+}
+
+// newSourceRef parses the path of a remote source and returns the ref along with the repository path to clone.
+// Paths are owner/repo optionally followed by tree/blob urls. Other paths on hosts other than GitHub,
+// i.e. GitLab subgroups (group/subgroup/repo) or Azure DevOps (org/project/_git/repo), are cloned as is.
+func newSourceRef(host string, path string) (*SourceRef, string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 {
+		return nil, "", fmt.Errorf("expected owner and repository in path '%s'", path)
+	}
+
+	// Segments become directories within the sync root and must not escape it
+	for _, segment := range segments {
+		if !isValidSegment(segment) {
+			return nil, "", fmt.Errorf("invalid segment '%s' in path '%s'", segment, path)
+		}
+	}
+
+	if !isValidSegment(host) {
+		return nil, "", fmt.Errorf("invalid host '%s'", host)
+	}
+
+	// GitLab separates the repository path from paths within the repo, i.e. group/subgroup/repo/-/tree/<ref>/<path>
+	repoSegments, rest := segments[:2], segments[2:]
+	if separator := slices.Index(segments, "-"); separator >= 2 {
+		repoSegments, rest = segments[:separator], segments[separator+1:]
+	} else if len(rest) > 0 && rest[0] != "tree" && rest[0] != "blob" && !strings.EqualFold(host, "github.com") {
+		repoSegments, rest = segments, nil
+	}
+
+	ref := &SourceRef{
+		Host:  strings.ToLower(host),
+		Owner: repoSegments[0],
+		Repo:  strings.TrimSuffix(repoSegments[1], ".git"),
+	}
+
+	if len(repoSegments) > 2 {
+		ref.Repo = nestedRepoName(repoSegments[1:])
+	}
+
+	// Paths within the repo, i.e. /tree/<ref>/<path> or /-/blob/<ref>/<path> for GitLab
+	if len(rest) > 0 {
+		if (rest[0] != "tree" && rest[0] != "blob") || len(rest) < 2 || rest[1] == "" {
+			return nil, "", fmt.Errorf("unsupported repository path '%s'", path)
+		}
+
+		ref.Ref = rest[1]
+		pathSegments := rest[2:]
+
+		// Blob urls point at a file, i.e. .../blob/main/templates/foo/azure.yaml
+		if rest[0] == "blob" && len(pathSegments) > 0 {
+			pathSegments = pathSegments[:len(pathSegments)-1]
+		}
+
+		ref.Path = strings.Join(pathSegments, "/")
+	}
+
+	repoPath := fmt.Sprintf("%s/%s", ref.Owner, ref.Repo)
+	if len(repoSegments) > 2 {
+		repoPath = strings.Join(repoSegments, "/")
+	}
+
+	return ref, repoPath, nil
+}
+
+// nestedRepoName returns a single directory name for a repository nested deeper than owner/repo so the clone
+// keeps the <host>/<owner>/<repo> layout. The hash of the nested path keeps names unique,
+// i.e. group/a-b/c and group/a/b-c.
+func nestedRepoName(segments []string) string {
+	nestedPath := strings.TrimSuffix(strings.Join(segments, "/"), ".git")
+	hash := sha256.Sum256([]byte(strings.ToLower(nestedPath)))
+
+	return fmt.Sprintf("%s-%s", strings.ReplaceAll(nestedPath, "/", "-"), hex.EncodeToString(hash[:4]))
+}
+
+func newLocalSourceRef(path string) (*SourceRef, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template source '%s': %w", path, err)
+	}
+
+	absPath = strings.TrimSuffix(absPath, string(filepath.Separator))
+
+	ref := &SourceRef{
+		Host:     localHost,
+		Owner:    filepath.Base(filepath.Dir(absPath)),
+		Repo:     strings.TrimSuffix(filepath.Base(absPath), ".git"),
+		CloneUrl: absPath,
+	}
+
+	if !isValidSegment(ref.Owner) || !isValidSegment(ref.Repo) {
+		return nil, fmt.Errorf("invalid template source '%s': expected a repository directory", path)
+	}
+
+	return ref, nil
+}
+
+// isValidSegment returns false for empty, relative and separator containing path segments.
+func isValidSegment(segment string) bool {
+	return segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, `/\`) &&
+		strings.TrimSuffix(segment, ".git") != ""
+}
+
+// RepoKey returns the canonical host/owner/repo key of the repository.
+// Keys are lower case so sources that only differ by case resolve to the same repository.
+func (s *SourceRef) RepoKey() string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s", s.Host, s.Owner, s.Repo))
+}
+
+// Key returns the canonical key of the template, the repo key followed by the template path within the repo.
+func (s *SourceRef) Key() string {
+	if s.Path == "" {
+		return s.RepoKey()
+	}
+
+	return fmt.Sprintf("%s/%s", s.RepoKey(), strings.ToLower(s.Path))
+}
+
+// RepoDir returns the directory of the repository clone within the sync root directory.
+// Templates sharing a repository share a single clone.
+func (s *SourceRef) RepoDir(root string) (string, error) {
+	return withinRoot(root, filepath.Join(root, filepath.FromSlash(s.RepoKey())))
+}
+
+// Dir returns the template directory within the sync root directory.
+func (s *SourceRef) Dir(root string) (string, error) {
+	repoDir, err := s.RepoDir(root)
+	if err != nil {
+		return "", err
+	}
+
+	return withinRoot(root, filepath.Join(repoDir, filepath.FromSlash(s.Path)))
+}
+
+// withinRoot returns the directory when it is located within the root directory.
+func withinRoot(root string, dir string) (string, error) {
+	relativePath, err := filepath.Rel(root, dir)
+	if err != nil || relativePath == "." || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("directory '%s' is outside of the sync directory '%s'", dir, root)
+	}
+
+	return dir, nil
+}
+
+// SourceKey returns the canonical key of the source,
+// falling back to a normalized form of the source when it cannot be parsed.
+func SourceKey(source string) string {
+	ref, err := ParseSource(source)
+	if err != nil {
+		normalized := strings.ToLower(strings.TrimSpace(source))
+		normalized = strings.TrimSuffix(normalized, "/")
+		normalized = strings.TrimSuffix(normalized, ".git")
+
+		return normalized
+	}
+
+	return ref.Key()
+}
+
+// RefConflicts returns the errors of the sources referencing a different branch of a repository than the first
+// source of the repository. Sources within a repository share a single clone checked out at one branch,
+// so only the branch of the first source is synced and the conflicting sources cannot be analyzed.
+func RefConflicts(sources []string) map[string]error {
+	conflicts := map[string]error{}
+	firstSources := map[string]string{}
+	firstRefs := map[string]*SourceRef{}
+
+	for _, source := range sources {
+		ref, err := ParseSource(source)
+		if err != nil {
+			continue
+		}
+
+		first, has := firstRefs[ref.RepoKey()]
+		if !has {
+			firstSources[ref.RepoKey()] = source
+			firstRefs[ref.RepoKey()] = ref
+			continue
+		}
+
+		if ref.Ref != first.Ref {
+			conflicts[source] = fmt.Errorf(
+				"%w: '%s' references %s, '%s' references %s",
+				ErrRefConflict,
+				source,
+				ref.branchName(),
+				firstSources[ref.RepoKey()],
+				first.branchName(),
+			)
+		}
+	}
+
+	return conflicts
+}
+
+func (s *SourceRef) branchName() string {
+	if s.Ref == "" {
+		return "the default branch"
+	}
+
+	return fmt.Sprintf("branch '%s'", s.Ref)
+}
+
+// RepoKey returns the canonical key of the repository of the source,
+// falling back to the source key when the source cannot be parsed.
+func RepoKey(source string) string {
+	ref, err := ParseSource(source)
+	if err != nil {
+		return SourceKey(source)
+	}
+
+	return ref.RepoKey()
+}
+
+// Dir returns the directory of the template source within the sync root directory.
+func Dir(root string, source string) (string, error) {
+	ref, err := ParseSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Dir(root)
+}
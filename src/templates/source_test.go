@@ -0,0 +1,218 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	localDir := filepath.Join(t.TempDir(), "samples", "todo.git")
+
+	tests := []struct {
+		name     string
+		source   string
+		expected *SourceRef
+		err      string
+	}{
+		{
+			name:   "https",
+			source: "https://github.com/Azure-Samples/todo-nodejs-mongo",
+			expected: &SourceRef{Host: "github.com", Owner: "Azure-Samples", Repo: "todo-nodejs-mongo",
+				CloneUrl: "https://github.com/Azure-Samples/todo-nodejs-mongo"},
+		},
+		{
+			name:   "git suffix and trailing slash",
+			source: "https://github.com/Azure-Samples/todo-nodejs-mongo.git/",
+			expected: &SourceRef{Host: "github.com", Owner: "Azure-Samples", Repo: "todo-nodejs-mongo",
+				CloneUrl: "https://github.com/Azure-Samples/todo-nodejs-mongo"},
+		},
+		{
+			name:   "host case",
+			source: "HTTPS://GitHub.com/Azure-Samples/todo-nodejs-mongo",
+			expected: &SourceRef{Host: "github.com", Owner: "Azure-Samples", Repo: "todo-nodejs-mongo",
+				CloneUrl: "https://GitHub.com/Azure-Samples/todo-nodejs-mongo"},
+		},
+		{
+			name:   "scp",
+			source: "git@github.com:Azure-Samples/todo-nodejs-mongo.git",
+			expected: &SourceRef{Host: "github.com", Owner: "Azure-Samples", Repo: "todo-nodejs-mongo",
+				CloneUrl: "git@github.com:Azure-Samples/todo-nodejs-mongo.git"},
+		},
+		{
+			name:   "tree",
+			source: "https://github.com/org/repo/tree/main/templates/foo",
+			expected: &SourceRef{Host: "github.com", Owner: "org", Repo: "repo", Ref: "main", Path: "templates/foo",
+				CloneUrl: "https://github.com/org/repo"},
+		},
+		{
+			name:   "blob",
+			source: "https://gitlab.com/org/repo/-/blob/dev/templates/foo/azure.yaml",
+			expected: &SourceRef{Host: "gitlab.com", Owner: "org", Repo: "repo", Ref: "dev", Path: "templates/foo",
+				CloneUrl: "https://gitlab.com/org/repo"},
+		},
+		{
+			name:   "gitlab subgroup",
+			source: "https://gitlab.com/group/sub/repo.git",
+			expected: &SourceRef{Host: "gitlab.com", Owner: "group", Repo: "sub-repo-d17e3cfa",
+				CloneUrl: "https://gitlab.com/group/sub/repo.git"},
+		},
+		{
+			name:   "gitlab subgroup tree",
+			source: "https://gitlab.com/group/Sub/repo/-/tree/v1.0/templates/foo",
+			expected: &SourceRef{Host: "gitlab.com", Owner: "group", Repo: "Sub-repo-d17e3cfa", Ref: "v1.0", Path: "templates/foo",
+				CloneUrl: "https://gitlab.com/group/Sub/repo"},
+		},
+		{
+			name:   "azure devops",
+			source: "https://org@dev.azure.com/org/project/_git/repo",
+			expected: &SourceRef{Host: "dev.azure.com", Owner: "org", Repo: "project-_git-repo-bd1ded7f",
+				CloneUrl: "https://org@dev.azure.com/org/project/_git/repo"},
+		},
+		{
+			name:   "local",
+			source: localDir,
+			expected: &SourceRef{Host: localHost, Owner: "samples", Repo: "todo",
+				CloneUrl: localDir},
+		},
+		{name: "empty", source: " ", err: "empty"},
+		{name: "missing repository", source: "https://github.com/Azure-Samples", err: "expected owner and repository"},
+		{name: "unsupported path", source: "https://github.com/org/repo/issues/1", err: "unsupported repository path"},
+		{name: "unsupported scheme", source: "ftp://github.com/org/repo", err: "unsupported scheme"},
+		{name: "parent owner and repo", source: "https://github.com/../..", err: "invalid segment '..'"},
+		{name: "current dir repo", source: "https://github.com/org/.", err: "invalid segment '.'"},
+		{name: "empty segment", source: "https://github.com/org//repo", err: "invalid segment ''"},
+		{name: "tree path traversal", source: "https://github.com/a/b/tree/main/../../../etc", err: "invalid segment '..'"},
+		{name: "scp traversal", source: "git@github.com:../repo.git", err: "invalid segment '..'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := ParseSource(test.source)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing '%s', got %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *ref != *test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, ref)
+			}
+		})
+	}
+}
+
+func TestSourceKey(t *testing.T) {
+	sources := []string{
+		"https://github.com/Azure-Samples/todo-nodejs-mongo",
+		"https://github.com/azure-samples/TODO-nodejs-mongo.git",
+		"git@github.com:Azure-Samples/todo-nodejs-mongo.git",
+		"ssh://git@github.com/Azure-Samples/todo-nodejs-mongo",
+	}
+
+	for _, source := range sources {
+		if key := SourceKey(source); key != "github.com/azure-samples/todo-nodejs-mongo" {
+			t.Errorf("unexpected key %s for %s", key, source)
+		}
+	}
+
+	if key := SourceKey("https://github.com/org/repo/tree/main/Templates/Foo"); key != "github.com/org/repo/templates/foo" {
+		t.Errorf("unexpected template key %s", key)
+	}
+
+	if key := RepoKey("https://github.com/org/repo/tree/main/templates/foo"); key != "github.com/org/repo" {
+		t.Errorf("unexpected repo key %s", key)
+	}
+
+	// Sources that cannot be parsed fall back to a normalized form
+	if key := SourceKey(" HTTPS://github.com/org/ "); key != "https://github.com/org" {
+		t.Errorf("unexpected fallback key %s", key)
+	}
+}
+
+func TestRefConflicts(t *testing.T) {
+	sources := []string{
+		"https://github.com/org/repo/tree/main/templates/a",
+		"https://github.com/org/repo/tree/main/templates/b",
+		"https://github.com/org/repo/tree/dev/templates/c",
+		"https://github.com/org/repo",
+		"https://github.com/org/other/tree/dev/templates/d",
+		"https://github.com/org/other/tree/dev/templates/e",
+	}
+
+	conflicts := RefConflicts(sources)
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", conflicts)
+	}
+
+	for _, source := range []string{sources[2], sources[3]} {
+		if err := conflicts[source]; !errors.Is(err, ErrRefConflict) {
+			t.Errorf("expected %s to conflict, got %v", source, err)
+		}
+	}
+}
+
+func TestDirWithinRoot(t *testing.T) {
+	root := t.TempDir()
+
+	dir, err := Dir(root, "https://github.com/org/repo/tree/main/templates/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := filepath.Join(root, "github.com", "org", "repo", "templates", "foo"); dir != expected {
+		t.Errorf("expected %s, got %s", expected, dir)
+	}
+
+	for _, ref := range []*SourceRef{
+		{Host: "github.com", Owner: "..", Repo: ".."},
+		{Host: "..", Owner: "..", Repo: ".."},
+		{Host: "github.com", Owner: "org", Repo: "repo", Path: "../../../../etc"},
+	} {
+		if dir, err := ref.Dir(root); err == nil {
+			t.Errorf("expected %+v to resolve outside of the root, got %s", ref, dir)
+		}
+	}
+}
+
+func TestMigrateLayout(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	root := t.TempDir()
+
+	// Legacy flat clone of the template and an unrelated repository with the same directory name
+	legacyDir := filepath.Join(root, "todo-nodejs-mongo")
+	remote.git(root, "clone", "--quiet", remote.bareDir, legacyDir)
+	remote.git(legacyDir, "remote", "set-url", "origin", testSource)
+
+	foreignSource := "https://github.com/contoso/app"
+	foreignDir := filepath.Join(root, "app")
+	remote.git(root, "clone", "--quiet", remote.bareDir, foreignDir)
+	remote.git(foreignDir, "remote", "set-url", "origin", "https://github.com/someone-else/app")
+
+	migrated, err := newTestSyncer(remote).MigrateLayout(t.Context(), root, []string{testSource, foreignSource})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(migrated, []string{testSource}) {
+		t.Errorf("expected only the template clone to be migrated, got %v", migrated)
+	}
+
+	if contents := readFile(t, filepath.Join(root, "github.com", "azure-samples", "todo-nodejs-mongo", "azure.yaml")); contents != "name: todo\n" {
+		t.Errorf("unexpected migrated azure.yaml contents %q", contents)
+	}
+
+	if _, err := os.Stat(foreignDir); err != nil {
+		t.Errorf("expected the foreign repository to stay in place: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Syncer clones and updates template repositories within a sync directory.
+type Syncer struct {
+	git Git
+}
+
+func NewSyncer(git Git) *Syncer {
+	return &Syncer{
+		git: git,
+	}
+}
+
+// Sync clones or updates the template source within the output directory
+// and returns the lock entry for the resulting revision.
+// The local branch is reset to the remote branch, local commits are discarded.
+// Refs that are not a remote branch, i.e. tags or commits, are checked out with a detached HEAD.
+func (s *Syncer) Sync(ctx context.Context, source string, outputDir string) (*LockEntry, error) {
+	ref, repoRoot, err := s.syncRepo(ctx, source, outputDir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	branch, err := s.branch(ctx, ref, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteBranch := fmt.Sprintf("origin/%s", branch)
+	if _, err := s.git.RevParse(ctx, repoRoot, remoteBranch); err != nil && ref.Ref != "" {
+		if err := s.git.Checkout(ctx, repoRoot, ref.Ref); err != nil {
+			return nil, fmt.Errorf("failed to checkout ref '%s': %w", ref.Ref, err)
+		}
+
+		return s.newLockEntry(ctx, source, repoRoot, branch)
+	}
+
+	if err := s.git.CheckoutBranch(ctx, repoRoot, branch, remoteBranch); err != nil {
+		return nil, fmt.Errorf("failed to checkout branch '%s': %w", branch, err)
+	}
+
+	return s.newLockEntry(ctx, source, repoRoot, branch)
+}
+
+// SyncAt clones or updates the template source within the output directory
+// and checks out the specified commit.
+func (s *Syncer) SyncAt(ctx context.Context, source string, outputDir string, commit string) (*LockEntry, error) {
+	ref, repoRoot, err := s.syncRepo(ctx, source, outputDir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.git.Checkout(ctx, repoRoot, commit); err != nil {
+		return nil, fmt.Errorf("failed to checkout commit '%s': %w", commit, err)
+	}
+
+	branch, err := s.branch(ctx, ref, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newLockEntry(ctx, source, repoRoot, branch)
+}
+
+// Checkout checks out the specified commit within an existing clone,
+// fetching from the remote when the commit is not available locally.
+func (s *Syncer) Checkout(ctx context.Context, repoRoot string, commit string) error {
+	if err := s.ensureClean(ctx, repoRoot); err != nil {
+		return err
+	}
+
+	if _, err := s.git.RevParse(ctx, repoRoot, commit); err != nil {
+		if err := s.git.Fetch(ctx, repoRoot); err != nil {
+			return fmt.Errorf("failed to fetch repo: %w", err)
+		}
+	}
+
+	if err := s.git.Checkout(ctx, repoRoot, commit); err != nil {
+		return fmt.Errorf("failed to checkout commit '%s': %w", commit, err)
+	}
+
+	return nil
+}
+
+// MigrateLayout moves clones from the legacy flat layout (<root>/<repo>) into the
+// owner-qualified layout (<root>/<host>/<owner>/<repo>) and returns the migrated sources.
+// A legacy clone is only moved when its origin remote resolves to the same repo key.
+func (s *Syncer) MigrateLayout(ctx context.Context, root string, sources []string) ([]string, error) {
+	migrated := []string{}
+
+	for _, source := range sources {
+		ref, err := ParseSource(source)
+		if err != nil {
+			continue
+		}
+
+		legacyDir, has := s.legacyClone(ctx, root, source)
+		if !has {
+			continue
+		}
+
+		repoDir, err := ref.RepoDir(root)
+		if err != nil {
+			return migrated, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(repoDir), 0755); err != nil {
+			return migrated, fmt.Errorf("failed to create directory for '%s': %w", repoDir, err)
+		}
+
+		if err := os.Rename(legacyDir, repoDir); err != nil {
+			return migrated, fmt.Errorf("failed to move '%s' to '%s': %w", legacyDir, repoDir, err)
+		}
+
+		migrated = append(migrated, source)
+	}
+
+	return migrated, nil
+}
+
+// legacyClone returns the legacy flat layout clone of the source that should be moved to the owner-qualified layout.
+func (s *Syncer) legacyClone(ctx context.Context, root string, source string) (string, bool) {
+	ref, err := ParseSource(source)
+	if err != nil {
+		return "", false
+	}
+
+	repoDir, err := ref.RepoDir(root)
+	if err != nil {
+		return "", false
+	}
+
+	legacyDir, err := withinRoot(root, filepath.Join(root, filepath.Base(strings.TrimSuffix(source, "/"))))
+	if err != nil || legacyDir == repoDir || !hasDir(legacyDir, ".git") {
+		return "", false
+	}
+
+	if _, err := os.Stat(repoDir); err == nil {
+		return "", false
+	}
+
+	remoteUrl, err := s.git.RemoteUrl(ctx, legacyDir)
+	if err != nil || RepoKey(remoteUrl) != ref.RepoKey() {
+		return "", false
+	}
+
+	return legacyDir, true
+}
+
+// syncRepo clones or fetches the repository of the template source.
+// Templates within the same repository share a single clone.
+// Existing clones are only fetched when commit is empty or not available locally.
+func (s *Syncer) syncRepo(ctx context.Context, source string, outputDir string, commit string) (*SourceRef, string, error) {
+	ref, err := ParseSource(source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repoRoot, err := ref.RepoDir(outputDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = os.Stat(repoRoot)
+	if err == nil {
+		if err := s.ensureClean(ctx, repoRoot); err != nil {
+			return nil, "", err
+		}
+
+		if commit != "" {
+			if _, err := s.git.RevParse(ctx, repoRoot, commit); err == nil {
+				return ref, repoRoot, nil
+			}
+		}
+
+		if err := s.git.Fetch(ctx, repoRoot); err != nil {
+			return nil, "", fmt.Errorf("failed to fetch repo: %w", err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(repoRoot), 0755); err != nil {
+			return nil, "", fmt.Errorf("failed to create directory for '%s': %w", repoRoot, err)
+		}
+
+		if err := s.git.Clone(ctx, ref.CloneUrl, repoRoot); err != nil {
+			return nil, "", fmt.Errorf("failed to clone repo: %w", err)
+		}
+	}
+
+	return ref, repoRoot, nil
+}
+
+func (s *Syncer) ensureClean(ctx context.Context, repoRoot string) error {
+	changes, err := s.git.Status(ctx, repoRoot)
+	if err != nil {
+		return fmt.Errorf("failed to get repo status: %w", err)
+	}
+
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %s", ErrDirtyWorkTree, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// branch returns the branch referenced by the source or the default branch of the remote.
+func (s *Syncer) branch(ctx context.Context, ref *SourceRef, repoRoot string) (string, error) {
+	if ref.Ref != "" {
+		return ref.Ref, nil
+	}
+
+	branch, err := s.git.DefaultBranch(ctx, repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve default branch: %w", err)
+	}
+
+	return branch, nil
+}
+
+func (s *Syncer) newLockEntry(ctx context.Context, source string, repoRoot string, branch string) (*LockEntry, error) {
+	commits, err := s.git.Log(ctx, repoRoot, "HEAD", 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve commit: %w", err)
+	}
+
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("failed to resolve commit: repository has no commits")
+	}
+
+	return &LockEntry{
+		Key:         RepoKey(source),
+		Source:      source,
+		Commit:      commits[0].Sha,
+		Branch:      branch,
+		CommittedAt: commits[0].CommittedAt,
+		SyncedAt:    time.Now().UTC(),
+	}, nil
+}
+
+func hasDir(root string, dirName string) bool {
+	info, err := os.Stat(filepath.Join(root, dirName))
+
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,476 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = "https://github.com/Azure-Samples/todo-nodejs-mongo"
+
+// localGit is a Git implementation that clones from local bare repositories instead of remote urls.
+type localGit struct {
+	Git
+	remotes map[string]string
+}
+
+func newLocalGit(remotes map[string]string) *localGit {
+	return &localGit{
+		Git:     NewGitCli(),
+		remotes: remotes,
+	}
+}
+
+func (g *localGit) Clone(ctx context.Context, url string, dir string) error {
+	remoteDir, has := g.remotes[RepoKey(url)]
+	if !has {
+		return errors.New("repository not found")
+	}
+
+	return g.Git.Clone(ctx, remoteDir, dir)
+}
+
+// testRemote is a local bare repository with a working clone used to push new commits.
+type testRemote struct {
+	t       *testing.T
+	bareDir string
+	workDir string
+}
+
+func newTestRemote(t *testing.T, branch string) *testRemote {
+	root := t.TempDir()
+	remote := &testRemote{
+		t:       t,
+		bareDir: filepath.Join(root, "remote.git"),
+		workDir: filepath.Join(root, "work"),
+	}
+
+	remote.git(root, "init", "--quiet", "--bare", "--initial-branch", branch, remote.bareDir)
+	remote.git(root, "clone", "--quiet", remote.bareDir, remote.workDir)
+	remote.git(remote.workDir, "checkout", "--quiet", "-b", branch)
+
+	return remote
+}
+
+func (r *testRemote) git(dir string, args ...string) string {
+	r.t.Helper()
+
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = dir
+	output, err := gitCmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// commit writes the file to the working clone, pushes it to the bare repository and returns the commit SHA.
+func (r *testRemote) commit(fileName string, content string) string {
+	r.t.Helper()
+
+	if err := os.WriteFile(filepath.Join(r.workDir, fileName), []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+
+	r.git(r.workDir, "add", fileName)
+	r.git(r.workDir, "commit", "--quiet", "-m", "update "+fileName)
+	r.git(r.workDir, "push", "--quiet", "origin", "HEAD")
+
+	return r.git(r.workDir, "rev-parse", "HEAD")
+}
+
+func newTestSyncer(remote *testRemote) *Syncer {
+	return NewSyncer(newLocalGit(map[string]string{
+		RepoKey(testSource): remote.bareDir,
+	}))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(contents)
+}
+
+func TestSyncFreshClone(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	commit := remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+
+	entry, err := newTestSyncer(remote).Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Commit != commit {
+		t.Errorf("expected commit %s, got %s", commit, entry.Commit)
+	}
+
+	if entry.Branch != "main" {
+		t.Errorf("expected branch main, got %s", entry.Branch)
+	}
+
+	if entry.Key != "github.com/azure-samples/todo-nodejs-mongo" {
+		t.Errorf("unexpected key %s", entry.Key)
+	}
+
+	repoDir := filepath.Join(outputDir, "github.com", "azure-samples", "todo-nodejs-mongo")
+	if contents := readFile(t, filepath.Join(repoDir, "azure.yaml")); contents != "name: todo\n" {
+		t.Errorf("unexpected azure.yaml contents %q", contents)
+	}
+}
+
+func TestSyncUpdate(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	if _, err := syncer.Sync(t.Context(), testSource, outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := remote.commit("azure.yaml", "name: todo-updated\n")
+
+	entry, err := syncer.Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Commit != commit {
+		t.Errorf("expected commit %s, got %s", commit, entry.Commit)
+	}
+
+	repoDir := filepath.Join(outputDir, "github.com", "azure-samples", "todo-nodejs-mongo")
+	if contents := readFile(t, filepath.Join(repoDir, "azure.yaml")); contents != "name: todo-updated\n" {
+		t.Errorf("unexpected azure.yaml contents %q", contents)
+	}
+}
+
+func TestSyncDirtyWorkTree(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	if _, err := syncer.Sync(t.Context(), testSource, outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	azureYamlPath := filepath.Join(outputDir, "github.com", "azure-samples", "todo-nodejs-mongo", "azure.yaml")
+	if err := os.WriteFile(azureYamlPath, []byte("name: local-change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.commit("azure.yaml", "name: todo-updated\n")
+
+	_, err := syncer.Sync(t.Context(), testSource, outputDir)
+	if !errors.Is(err, ErrDirtyWorkTree) {
+		t.Fatalf("expected dirty work tree error, got %v", err)
+	}
+
+	if contents := readFile(t, azureYamlPath); contents != "name: local-change\n" {
+		t.Errorf("expected local changes to be preserved, got %q", contents)
+	}
+}
+
+func TestSyncDetachedHead(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	firstCommit := remote.commit("azure.yaml", "name: todo\n")
+	latestCommit := remote.commit("azure.yaml", "name: todo-updated\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	entry, err := syncer.SyncAt(t.Context(), testSource, outputDir, firstCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Commit != firstCommit {
+		t.Fatalf("expected commit %s, got %s", firstCommit, entry.Commit)
+	}
+
+	repoDir := filepath.Join(outputDir, "github.com", "azure-samples", "todo-nodejs-mongo")
+	if head := remote.git(repoDir, "rev-parse", "--abbrev-ref", "HEAD"); head != "HEAD" {
+		t.Fatalf("expected detached HEAD, got %s", head)
+	}
+
+	entry, err = syncer.Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Commit != latestCommit {
+		t.Errorf("expected commit %s, got %s", latestCommit, entry.Commit)
+	}
+
+	if head := remote.git(repoDir, "rev-parse", "--abbrev-ref", "HEAD"); head != "main" {
+		t.Errorf("expected branch main to be checked out, got %s", head)
+	}
+}
+
+func TestSyncTag(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	taggedCommit := remote.commit("azure.yaml", "name: todo\n")
+	remote.git(remote.workDir, "tag", "--annotate", "-m", "release", "v1.0")
+	remote.git(remote.workDir, "push", "--quiet", "origin", "v1.0")
+	latestCommit := remote.commit("azure.yaml", "name: todo-updated\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	for _, ref := range []string{"v1.0", taggedCommit} {
+		entry, err := syncer.Sync(t.Context(), testSource+"/tree/"+ref, outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Commit != taggedCommit {
+			t.Errorf("expected commit %s for %s, got %s", taggedCommit, ref, entry.Commit)
+		}
+
+		plan, err := syncer.Plan(t.Context(), outputDir, []string{testSource + "/tree/" + ref}, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if plan.Items[0].Action != SyncUnchanged {
+			t.Errorf("expected %s to be unchanged, got %s %s", ref, plan.Items[0].Action, plan.Items[0].Note)
+		}
+	}
+
+	entry, err := syncer.Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Commit != latestCommit {
+		t.Errorf("expected commit %s for the default branch, got %s", latestCommit, entry.Commit)
+	}
+}
+
+func TestSyncRenamedDefaultBranch(t *testing.T) {
+	remote := newTestRemote(t, "master")
+	remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	entry, err := syncer.Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Branch != "master" {
+		t.Fatalf("expected branch master, got %s", entry.Branch)
+	}
+
+	// Rename the default branch on the remote
+	remote.git(remote.bareDir, "branch", "-m", "master", "main")
+	remote.git(remote.bareDir, "symbolic-ref", "HEAD", "refs/heads/main")
+	remote.git(remote.workDir, "fetch", "--quiet", "--prune", "origin")
+	remote.git(remote.workDir, "checkout", "--quiet", "-B", "main", "origin/main")
+	commit := remote.commit("azure.yaml", "name: todo-updated\n")
+
+	entry, err = syncer.Sync(t.Context(), testSource, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Branch != "main" {
+		t.Errorf("expected branch main, got %s", entry.Branch)
+	}
+
+	if entry.Commit != commit {
+		t.Errorf("expected commit %s, got %s", commit, entry.Commit)
+	}
+}
+
+func TestSyncRepositoryNotFound(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+
+	_, err := newTestSyncer(remote).Sync(t.Context(), "https://github.com/Azure-Samples/missing", t.TempDir())
+	if err == nil {
+		t.Fatal("expected error for missing repository")
+	}
+}
+
+func TestSyncCancelled(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := newTestSyncer(remote).Sync(ctx, testSource, t.TempDir())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancelled error, got %v", err)
+	}
+}
+
+func TestSyncPlan(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+	sources := []string{testSource}
+
+	planAction := func() SyncAction {
+		t.Helper()
+
+		plan, err := syncer.Plan(t.Context(), outputDir, sources, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(plan.Items) != 1 {
+			t.Fatalf("expected 1 plan item, got %d", len(plan.Items))
+		}
+
+		return plan.Items[0].Action
+	}
+
+	if action := planAction(); action != SyncClone {
+		t.Errorf("expected clone before first sync, got %s", action)
+	}
+
+	if _, err := syncer.Sync(t.Context(), testSource, outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if action := planAction(); action != SyncUnchanged {
+		t.Errorf("expected unchanged after sync, got %s", action)
+	}
+
+	remote.commit("azure.yaml", "name: todo-updated\n")
+
+	if action := planAction(); action != SyncUpdate {
+		t.Errorf("expected update after remote commit, got %s", action)
+	}
+
+	staleDir := filepath.Join(outputDir, "github.com", "azure-samples", "removed")
+	remote.git(outputDir, "clone", "--quiet", remote.bareDir, staleDir)
+	remote.git(staleDir, "remote", "set-url", "origin", "https://github.com/Azure-Samples/removed")
+
+	plan, err := syncer.Plan(t.Context(), outputDir, sources, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Count(SyncRemove) != 1 {
+		t.Fatalf("expected 1 clone to remove, got %d", plan.Count(SyncRemove))
+	}
+
+	if _, err := os.Stat(staleDir); err != nil {
+		t.Fatalf("expected plan to not modify the sync directory: %v", err)
+	}
+
+	if _, err := plan.Prune(outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(staleDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected stale clone to be removed, got %v", err)
+	}
+}
+
+func TestSyncPlanInvalidSource(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	sources := []string{"https://github.com/Azure-Samples", testSource}
+
+	plan, err := newTestSyncer(remote).Plan(t.Context(), t.TempDir(), sources, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Items) != 2 {
+		t.Fatalf("expected 2 plan items, got %d", len(plan.Items))
+	}
+
+	if plan.Count(SyncSkip) != 1 || plan.Count(SyncClone) != 1 {
+		t.Errorf("expected the invalid source to be skipped and the valid source to be cloned, got %+v %+v", plan.Items[0], plan.Items[1])
+	}
+
+	for _, item := range plan.Items {
+		if item.Action == SyncSkip && !strings.Contains(item.Note, "expected owner and repository") {
+			t.Errorf("expected parse error note, got %s", item.Note)
+		}
+	}
+}
+
+func TestStaleClonesKeepsDirtyAndForeignClones(t *testing.T) {
+	remote := newTestRemote(t, "main")
+	remote.commit("azure.yaml", "name: todo\n")
+	outputDir := t.TempDir()
+	syncer := newTestSyncer(remote)
+
+	clone := func(dir string, origin string) string {
+		cloneDir := filepath.Join(outputDir, dir)
+		remote.git(outputDir, "clone", "--quiet", remote.bareDir, cloneDir)
+		remote.git(cloneDir, "remote", "set-url", "origin", origin)
+
+		return cloneDir
+	}
+
+	staleDir := clone("github.com/azure-samples/removed", "https://github.com/Azure-Samples/removed")
+	dirtyDir := clone("github.com/azure-samples/dirty", "https://github.com/Azure-Samples/dirty")
+	foreignDir := clone("app", "https://github.com/contoso/app")
+	movedDir := clone("github.com/azure-samples/renamed", "https://github.com/Azure-Samples/other")
+
+	if err := os.WriteFile(filepath.Join(dirtyDir, "azure.yaml"), []byte("name: local-change\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := syncer.StaleClones(t.Context(), outputDir, []string{testSource})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]SyncAction{}
+	for _, item := range plan.Items {
+		actions[item.Key] = item.Action
+	}
+
+	expected := map[string]SyncAction{
+		"github.com/azure-samples/removed": SyncRemove,
+		"github.com/azure-samples/dirty":   SyncSkip,
+	}
+
+	if len(actions) != len(expected) {
+		t.Fatalf("expected plan %v, got %v", expected, actions)
+	}
+
+	for key, action := range expected {
+		if actions[key] != action {
+			t.Errorf("expected %s for %s, got %s", action, key, actions[key])
+		}
+	}
+
+	if _, err := plan.Prune(outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(staleDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected stale clone to be removed, got %v", err)
+	}
+
+	for _, dir := range []string{dirtyDir, foreignDir, movedDir} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("expected %s to not be removed: %v", dir, err)
+		}
+	}
+
+	if contents := readFile(t, filepath.Join(dirtyDir, "azure.yaml")); contents != "name: local-change\n" {
+		t.Errorf("expected local changes to be preserved, got %q", contents)
+	}
+}
@@ -1,91 +1,144 @@
 package templates
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"reflect"
 	"slices"
+	"strings"
 )
 
+// Template is a catalog entry. Fields not modeled by the struct are preserved in Extra
+// so the entry round-trips without losing catalog attributes.
 type Template struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Website     string   `json:"website"`
-	Author      string   `json:"author"`
-	Source      string   `json:"source"`
-	Tags        []string `json:"tags"`
+	Id            string   `json:"id,omitempty"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Preview       string   `json:"preview,omitempty"`
+	Website       string   `json:"website"`
+	Author        string   `json:"author"`
+	AuthorUrl     string   `json:"authorUrl,omitempty"`
+	Source        string   `json:"source"`
+	Tags          []string `json:"tags"`
+	Languages     []string `json:"languages,omitempty"`
+	Frameworks    []string `json:"frameworks,omitempty"`
+	AzureServices []string `json:"azureServices,omitempty"`
+	IaC           []string `json:"IaC,omitempty"`
+	Catalog       string   `json:"catalog,omitempty"`
+
+	// Extra holds the raw catalog fields without a typed field.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
-func Load(path string) ([]*Template, error) {
-	templateBytes, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+// templateFields is used to marshal the typed fields without recursing into the custom marshalers.
+type templateFields Template
+
+// knownFields are the json names of the typed template fields.
+var knownFields = func() map[string]bool {
+	fields := map[string]bool{}
+	fieldsType := reflect.TypeFor[templateFields]()
+
+	for i := 0; i < fieldsType.NumField(); i++ {
+		name, _, _ := strings.Cut(fieldsType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
 	}
 
-	var templates []*Template
-	if err := json.Unmarshal(templateBytes, &templates); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal file %s: %w", path, err)
+	return fields
+}()
+
+func (t *Template) UnmarshalJSON(data []byte) error {
+	var fields templateFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
 	}
 
-	slices.SortFunc(templates, func(a *Template, b *Template) int {
-		if a.Title < b.Title {
-			return -1
-		} else if a.Title > b.Title {
-			return 1
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for name := range raw {
+		if knownFields[name] {
+			delete(raw, name)
 		}
+	}
 
-		return 0
-	})
+	if len(raw) > 0 {
+		fields.Extra = raw
+	} else {
+		fields.Extra = nil
+	}
 
-	return templates, nil
+	*t = Template(fields)
+
+	return nil
 }
 
-func GetTemplates(url string) ([]*Template, error) {
-	res, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download templates: %w", err)
+// MarshalJSON writes the typed fields followed by the extra fields sorted by name.
+func (t Template) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(templateFields(t))
+	if err != nil || len(t.Extra) == 0 {
+		return data, err
 	}
 
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed reading response body: %w", err)
+	names := []string{}
+	for name := range t.Extra {
+		if !knownFields[name] {
+			names = append(names, name)
+		}
 	}
 
-	var templates []*Template
-	if err := json.Unmarshal(body, &templates); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal templates: %w", err)
+	slices.Sort(names)
+
+	var buffer bytes.Buffer
+	buffer.Write(data[:len(data)-1])
+
+	for _, name := range names {
+		nameBytes, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+
+		value := t.Extra[name]
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("invalid value for template field '%s'", name)
+		}
+
+		buffer.WriteByte(',')
+		buffer.Write(nameBytes)
+		buffer.WriteByte(':')
+		buffer.Write(value)
 	}
 
-	return templates, nil
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
 }
 
-func Sync(source string, outputDir string) error {
-	_, err := os.Stat(outputDir)
+func Load(path string) ([]*Template, error) {
+	templateBytes, err := os.ReadFile(path)
 	if err != nil {
-		os.MkdirAll(outputDir, 0755)
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
 	}
 
-	repoRoot := filepath.Join(outputDir, filepath.Base(source))
-	_, err = os.Stat(repoRoot)
-	if err == nil {
-		pullCmd := exec.Command("git", "pull")
-		pullCmd.Dir = repoRoot
-		if err := pullCmd.Run(); err != nil {
-			return fmt.Errorf("failed to pull repo: %w", err)
-		}
-	} else {
-		cloneCmd := exec.Command("git", "clone", source)
-		cloneCmd.Dir = outputDir
-		if err := cloneCmd.Run(); err != nil {
-			return fmt.Errorf("failed to clone repo: %w", err)
-		}
+	var templates []*Template
+	if err := json.Unmarshal(templateBytes, &templates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file %s: %w", path, err)
 	}
 
-	return nil
+	slices.SortFunc(templates, func(a *Template, b *Template) int {
+		if a.Title < b.Title {
+			return -1
+		} else if a.Title > b.Title {
+			return 1
+		}
+
+		return 0
+	})
+
+	return templates, nil
 }
@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestTemplateRoundTrip(t *testing.T) {
+	entry := `{
+		"id": "todo-nodejs-mongo",
+		"title": "Todo",
+		"description": "A todo app",
+		"preview": "./templates/images/todo.png",
+		"author": "Azure Dev",
+		"authorUrl": "https://github.com/azure-samples",
+		"source": "https://github.com/Azure-Samples/todo-nodejs-mongo",
+		"tags": ["msft"],
+		"languages": ["nodejs", "typescript"],
+		"azureServices": ["cosmosdb", "appservice"],
+		"IaC": ["bicep", "terraform"],
+		"platformTypes": ["web"],
+		"quickstart": {"command": "azd init -t todo-nodejs-mongo"}
+	}`
+
+	var template Template
+	if err := json.Unmarshal([]byte(entry), &template); err != nil {
+		t.Fatal(err)
+	}
+
+	if template.Id != "todo-nodejs-mongo" || template.AuthorUrl != "https://github.com/azure-samples" {
+		t.Errorf("unexpected typed fields %+v", template)
+	}
+
+	if !slices.Equal(template.IaC, []string{"bicep", "terraform"}) {
+		t.Errorf("unexpected IaC %v", template.IaC)
+	}
+
+	if len(template.Extra) != 2 || template.Extra["platformTypes"] == nil {
+		t.Fatalf("expected 2 extra fields, got %v", template.Extra)
+	}
+
+	template.Catalog = "https://example.com/templates.json"
+
+	data, err := json.Marshal(&template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected map[string]any
+	if err := json.Unmarshal([]byte(entry), &expected); err != nil {
+		t.Fatal(err)
+	}
+	expected["catalog"] = template.Catalog
+	expected["website"] = ""
+
+	var actual map[string]any
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBytes, _ := json.Marshal(expected)
+	actualBytes, _ := json.Marshal(actual)
+	if string(expectedBytes) != string(actualBytes) {
+		t.Errorf("expected round trip\n%s\ngot\n%s", expectedBytes, actualBytes)
+	}
+}
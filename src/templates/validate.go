@@ -0,0 +1,241 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ValidationSeverity string
+
+const (
+	SeverityError   ValidationSeverity = "error"
+	SeverityWarning ValidationSeverity = "warning"
+)
+
+// ValidationRule identifies the catalog check that reported an issue.
+type ValidationRule string
+
+const (
+	RuleMissingSource   ValidationRule = "missingSource"
+	RuleInvalidSource   ValidationRule = "invalidSource"
+	RuleDuplicateSource ValidationRule = "duplicateSource"
+	RuleMissingTitle    ValidationRule = "missingTitle"
+	RuleDuplicateTitle  ValidationRule = "duplicateTitle"
+	RuleEmptyTags       ValidationRule = "emptyTags"
+	RuleUnknownTag      ValidationRule = "unknownTag"
+	RuleAuthorFormat    ValidationRule = "authorFormat"
+)
+
+// Taxonomy lists the allowed values of the catalog tag fields.
+// Fields without allowed values are not checked.
+type Taxonomy struct {
+	Tags          []string `yaml:"tags" json:"tags"`
+	Languages     []string `yaml:"languages" json:"languages"`
+	Frameworks    []string `yaml:"frameworks" json:"frameworks"`
+	AzureServices []string `yaml:"azureServices" json:"azureServices"`
+	IaC           []string `yaml:"IaC" json:"IaC"`
+}
+
+// LoadTaxonomy loads a YAML or JSON taxonomy file.
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	taxonomyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read taxonomy %s: %w", path, err)
+	}
+
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal(taxonomyBytes, &taxonomy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal taxonomy %s: %w", path, err)
+	}
+
+	return &taxonomy, nil
+}
+
+// ValidationIssue is a single problem found in a catalog entry.
+type ValidationIssue struct {
+	// Index is the position of the template within the catalog.
+	Index    int                `json:"index"`
+	Title    string             `json:"title"`
+	Source   string             `json:"source"`
+	Field    string             `json:"field"`
+	Rule     ValidationRule     `json:"rule"`
+	Severity ValidationSeverity `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+// ValidationReport lists the issues found in a catalog.
+type ValidationReport struct {
+	Catalog   string             `json:"catalog"`
+	Templates int                `json:"templates"`
+	Issues    []*ValidationIssue `json:"issues"`
+}
+
+// Count returns the number of issues with the specified severity.
+func (r *ValidationReport) Count(severity ValidationSeverity) int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ValidateCatalog checks the catalog templates in catalog order. Tags are checked against the taxonomy when specified.
+func ValidateCatalog(catalog string, catalogTemplates []*Template, taxonomy *Taxonomy) *ValidationReport {
+	report := &ValidationReport{
+		Catalog:   catalog,
+		Templates: len(catalogTemplates),
+		Issues:    []*ValidationIssue{},
+	}
+
+	sources := map[string]*Template{}
+	titles := map[string]*Template{}
+
+	for i, template := range catalogTemplates {
+		addIssue := func(field string, rule ValidationRule, severity ValidationSeverity, format string, args ...any) {
+			report.Issues = append(report.Issues, &ValidationIssue{
+				Index:    i,
+				Title:    template.Title,
+				Source:   template.Source,
+				Field:    field,
+				Rule:     rule,
+				Severity: severity,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		title := strings.ToLower(strings.TrimSpace(template.Title))
+		if title == "" {
+			addIssue("title", RuleMissingTitle, SeverityError, "title is empty")
+		} else if existing, has := titles[title]; has {
+			addIssue("title", RuleDuplicateTitle, SeverityError, "title duplicates the template with source '%s'", existing.Source)
+		} else {
+			titles[title] = template
+		}
+
+		if err := validateSource(template.Source); err != nil {
+			rule := RuleInvalidSource
+			if strings.TrimSpace(template.Source) == "" {
+				rule = RuleMissingSource
+			}
+
+			addIssue("source", rule, SeverityError, "%v", err)
+		} else {
+			key := SourceKey(template.Source)
+			if existing, has := sources[key]; has {
+				addIssue("source", RuleDuplicateSource, SeverityError, "source duplicates the template '%s'", existing.Title)
+			} else {
+				sources[key] = template
+			}
+		}
+
+		if len(template.Tags) == 0 {
+			addIssue("tags", RuleEmptyTags, SeverityWarning, "template has no tags")
+		}
+
+		for tagIndex, tag := range template.Tags {
+			if strings.TrimSpace(tag) == "" {
+				addIssue("tags", RuleEmptyTags, SeverityWarning, "tag %d is empty", tagIndex)
+			}
+		}
+
+		if taxonomy != nil {
+			fields := []struct {
+				name    string
+				values  []string
+				allowed []string
+			}{
+				{"tags", template.Tags, taxonomy.Tags},
+				{"languages", template.Languages, taxonomy.Languages},
+				{"frameworks", template.Frameworks, taxonomy.Frameworks},
+				{"azureServices", template.AzureServices, taxonomy.AzureServices},
+				{"IaC", template.IaC, taxonomy.IaC},
+			}
+
+			for _, field := range fields {
+				if len(field.allowed) == 0 {
+					continue
+				}
+
+				for _, value := range field.values {
+					if strings.TrimSpace(value) != "" && !slices.Contains(field.allowed, value) {
+						addIssue(field.name, RuleUnknownTag, SeverityWarning, "value '%s' is not in the taxonomy", value)
+					}
+				}
+			}
+		}
+
+		for _, message := range authorIssues(template) {
+			addIssue("author", RuleAuthorFormat, SeverityWarning, "%s", message)
+		}
+	}
+
+	return report
+}
+
+// validateSource checks the source is a web url of a repository that can be synced.
+func validateSource(source string) error {
+	if strings.TrimSpace(source) == "" {
+		return fmt.Errorf("source is empty")
+	}
+
+	if source != strings.TrimSpace(source) {
+		return fmt.Errorf("source '%s' has leading or trailing whitespace", source)
+	}
+
+	parsedUrl, err := url.Parse(source)
+	if err != nil || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") {
+		return fmt.Errorf("source '%s' is not an http(s) url", source)
+	}
+
+	if _, err := ParseSource(source); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// authorIssues returns the formatting problems of the comma separated author list and author url.
+func authorIssues(template *Template) []string {
+	issues := []string{}
+	author := template.Author
+
+	if strings.TrimSpace(author) == "" {
+		return append(issues, "author is empty")
+	}
+
+	if author != strings.TrimSpace(author) {
+		issues = append(issues, "author has leading or trailing whitespace")
+	}
+
+	if strings.Contains(author, "  ") {
+		issues = append(issues, "author contains repeated spaces")
+	}
+
+	if strings.Contains(author, ";") {
+		issues = append(issues, "authors should be separated by commas instead of semicolons")
+	}
+
+	for _, name := range strings.Split(author, ",") {
+		if strings.TrimSpace(name) == "" {
+			issues = append(issues, "author list contains an empty name")
+			break
+		}
+	}
+
+	if template.AuthorUrl != "" {
+		parsedUrl, err := url.Parse(template.AuthorUrl)
+		if err != nil || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") || parsedUrl.Host == "" {
+			issues = append(issues, fmt.Sprintf("author url '%s' is not an http(s) url", template.AuthorUrl))
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,46 @@
+package templates
+
+import (
+	"testing"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	catalogTemplates := []*Template{
+		{Title: "Todo", Author: "Azure Dev", Source: "https://github.com/Azure-Samples/todo-nodejs-mongo", Tags: []string{"msft", "unknown"}},
+		{Title: "todo", Author: "Azure Dev,", Source: "https://github.com/azure-samples/todo-nodejs-mongo.git", Tags: []string{"msft"}},
+		{Title: "Local", Author: "Contoso", Source: "./local", Tags: []string{}},
+		{Title: "Missing", Author: "Contoso", Tags: []string{"community"}},
+	}
+
+	report := ValidateCatalog("catalog.json", catalogTemplates, &Taxonomy{Tags: []string{"msft", "community"}})
+
+	expected := map[int][]ValidationRule{
+		0: {RuleUnknownTag},
+		1: {RuleDuplicateTitle, RuleDuplicateSource, RuleAuthorFormat},
+		2: {RuleInvalidSource, RuleEmptyTags},
+		3: {RuleMissingSource},
+	}
+
+	actual := map[int][]ValidationRule{}
+	for _, issue := range report.Issues {
+		actual[issue.Index] = append(actual[issue.Index], issue.Rule)
+	}
+
+	for index, rules := range expected {
+		if len(actual[index]) != len(rules) {
+			t.Errorf("template %d: expected rules %v, got %v", index, rules, actual[index])
+			continue
+		}
+
+		for i, rule := range rules {
+			if actual[index][i] != rule {
+				t.Errorf("template %d: expected rules %v, got %v", index, rules, actual[index])
+				break
+			}
+		}
+	}
+
+	if report.Count(SeverityError) != 4 {
+		t.Errorf("expected 4 errors, got %d", report.Count(SeverityError))
+	}
+}